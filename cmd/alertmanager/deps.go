@@ -5,17 +5,21 @@ package main
 import (
 	"context"
 	"fmt"
-	"regexp"
 	"time"
 
 	"github.com/byuoitav/auth/wso2"
 	"github.com/byuoitav/smee/internal/app/alertmanager"
+	"github.com/byuoitav/smee/internal/app/alertmanager/config"
 	"github.com/byuoitav/smee/internal/app/alertmanager/incidents"
 	"github.com/byuoitav/smee/internal/app/alertmanager/issuecache"
 	"github.com/byuoitav/smee/internal/app/alertmanager/maintenance"
+	"github.com/byuoitav/smee/internal/app/alertmanager/notify"
 	"github.com/byuoitav/smee/internal/app/alertmanager/redis"
+	"github.com/byuoitav/smee/internal/app/alertmanager/shadow"
+	"github.com/byuoitav/smee/internal/app/alertmanager/silence"
 	"github.com/byuoitav/smee/internal/app/commandcli"
 	"github.com/byuoitav/smee/internal/pkg/couch"
+	"github.com/byuoitav/smee/internal/pkg/eventlog"
 	"github.com/byuoitav/smee/internal/pkg/messenger"
 	"github.com/byuoitav/smee/internal/pkg/postgres"
 	"github.com/byuoitav/smee/internal/pkg/servicenow"
@@ -37,10 +41,13 @@ func (d *Deps) build() {
 	d.buildIncidentStore()
 	d.buildIssueCache(ctx)
 	d.buildMaintenanceCache(ctx)
+	d.buildSilenceCache(ctx)
 	d.buildIssueTypeStore(ctx)
 
 	// Disable building alert management stuff if we have disabled it
 	if !d.DisableAlertManager {
+		d.buildAlertConfig()
+		d.buildNotifier()
 		d.buildEventStreamer()
 		d.buildDeviceStateStore(ctx)
 		d.buildAlertManager()
@@ -55,6 +62,10 @@ func (d *Deps) build() {
 func (d *Deps) cleanup() {
 	d.log.Sync()       // nolint:errcheck
 	d.postgres.Close() // nolint:errcheck
+
+	if d.eventRecorder != nil {
+		d.eventRecorder.Close() // nolint:errcheck
+	}
 }
 
 func (d *Deps) buildIncidentMaintenanceStore(ctx context.Context) {
@@ -68,6 +79,7 @@ func (d *Deps) buildIncidentMaintenanceStore(ctx context.Context) {
 	d.postgres = store
 	d.issueStore = store
 	d.maintenanceStore = store
+	d.silenceStore = store
 }
 
 func (d *Deps) buildIssueTypeStore(ctx context.Context) {
@@ -75,10 +87,16 @@ func (d *Deps) buildIssueTypeStore(ctx context.Context) {
 }
 
 func (d *Deps) buildIssueCache(ctx context.Context) {
+	// routes are added once the alert config is loaded
+	d.notifier = &notify.Router{
+		Log: d.log.Named("notify"),
+	}
+
 	cache := &issuecache.Cache{
 		Log:           d.log.Named("issue-cache"),
 		IncidentStore: d.incidentStore,
 		IssueStore:    d.issueStore,
+		Notifier:      d.notifier,
 	}
 
 	if err := cache.Sync(ctx); err != nil {
@@ -101,6 +119,19 @@ func (d *Deps) buildMaintenanceCache(ctx context.Context) {
 	d.maintenanceStore = cache
 }
 
+func (d *Deps) buildSilenceCache(ctx context.Context) {
+	cache := &silence.Cache{
+		Log:          d.log.Named("silence-cache"),
+		SilenceStore: d.silenceStore,
+	}
+
+	if err := cache.Sync(ctx); err != nil {
+		d.log.Fatal("unable to sync silence cache", zap.Error(err))
+	}
+
+	d.silenceStore = cache
+}
+
 func (d *Deps) buildIncidentStore() {
 	d.incidentStore = &incidents.Store{
 		Client: &servicenow.Client{
@@ -114,14 +145,36 @@ func (d *Deps) buildIncidentStore() {
 }
 
 func (d *Deps) buildEventStreamer() {
-	if d.HubURL == "" {
+	var base smee.EventStreamer
+
+	switch {
+	case d.ReplayEventsFile != "":
+		d.log.Info("Replaying events instead of connecting to the hub", zap.String("file", d.ReplayEventsFile), zap.Float64("speed", d.ReplaySpeed))
+		base = &eventlog.Replayer{
+			Path:  d.ReplayEventsFile,
+			Speed: d.ReplaySpeed,
+		}
+	case d.HubURL == "":
 		d.log.Fatal("invalid hub url")
+	default:
+		base = &messenger.Messenger{
+			HubURL: d.HubURL,
+		}
+	}
+
+	if d.RecordEventsFile != "" {
+		// record the base stream so that each event is only recorded once
+		d.eventRecorder = &eventlog.Recorder{
+			EventStreamer: base,
+			Path:          d.RecordEventsFile,
+			Log:           d.log.Named("event-recorder"),
+		}
+
+		base = d.eventRecorder
 	}
 
 	d.eventStreamer = &streamwrapper.StreamWrapper{
-		EventStreamer: &messenger.Messenger{
-			HubURL: d.HubURL,
-		},
+		EventStreamer: base,
 	}
 }
 
@@ -131,224 +184,74 @@ func (d *Deps) buildDeviceStateStore(ctx context.Context) {
 		d.log.Fatal("unable to build redis store", zap.Error(err))
 	}
 
+	store.Log = d.log.Named("redis")
+
 	d.deviceStateStore = store
 }
 
+func (d *Deps) buildAlertConfig() {
+	cfg, err := config.Load(d.AlertConfigFile)
+	if err != nil {
+		d.log.Fatal("invalid alert config", zap.Error(err))
+	}
+
+	d.alertConfig = cfg
+}
+
+func (d *Deps) buildNotifier() {
+	notifiers, routes, err := notify.FromConfig(d.alertConfig)
+	if err != nil {
+		d.log.Fatal("unable to build notifiers", zap.Error(err))
+	}
+
+	d.notifier.Set(notifiers, routes)
+}
+
+func (d *Deps) reloadNotifier(cfg config.Config) {
+	notifiers, routes, err := notify.FromConfig(cfg)
+	if err != nil {
+		d.log.Error("unable to rebuild notifiers, keeping previous notifiers", zap.Error(err))
+		return
+	}
+
+	d.notifier.Set(notifiers, routes)
+}
+
 func (d *Deps) buildAlertManager() {
+	d.shadowStore = &shadow.Store{}
+
 	d.alertManager = &alertmanager.Manager{
-		IssueStore:       d.issueStore,
-		MaintenanceStore: d.maintenanceStore,
-		EventStreamer:    d.eventStreamer,
-		DeviceStateStore: d.deviceStateStore,
-		AlertConfigs: map[string]smee.AlertConfig{
-			"cpu-temperature": {
-				Create: smee.AlertTransition{
-					Event: &smee.AlertTransitionEvent{
-						KeyMatches:   regexp.MustCompile("thermal0-temp"),
-						ValueMatches: regexp.MustCompile(`^([8-9][0-9]|[1-9][0-9]{2,})(\.[0-9]*)*$`),
-					},
-				},
-				Close: smee.AlertTransition{
-					Event: &smee.AlertTransitionEvent{
-						KeyMatches:   regexp.MustCompile("thermal0-temp"),
-						ValueMatches: regexp.MustCompile(`^0*([0-9]|[1-6][0-9])(\.[0-9]*)*$`),
-					},
-				},
-			},
-			"device-comm": {
-				Create: smee.AlertTransition{
-					Event: &smee.AlertTransitionEvent{
-						KeyMatches:        regexp.MustCompile("^responsive$"),
-						ValueDoesNotMatch: regexp.MustCompile("^Ok$"),
-					},
-				},
-				Close: smee.AlertTransition{
-					Event: &smee.AlertTransitionEvent{
-						KeyMatches:   regexp.MustCompile("^responsive$"),
-						ValueMatches: regexp.MustCompile("^Ok$"),
-					},
-				},
-			},
-			"device-offline": {
-				Create: smee.AlertTransition{
-					Event: &smee.AlertTransitionEvent{
-						KeyMatches:        regexp.MustCompile("^online$"),
-						ValueDoesNotMatch: regexp.MustCompile("^Online$"),
-					},
-				},
-				Close: smee.AlertTransition{
-					Event: &smee.AlertTransitionEvent{
-						KeyMatches:   regexp.MustCompile("^online$"),
-						ValueMatches: regexp.MustCompile("^Online$"),
-					},
-				},
-			},
-			"lamp-warning": {
-				Create: smee.AlertTransition{
-					Event: &smee.AlertTransitionEvent{
-						KeyMatches:   regexp.MustCompile("status-message"),
-						ValueMatches: regexp.MustCompile("(?i)WARNING|Communication|AROUND LAMP TEMPERATURE"),
-					},
-				},
-				Close: smee.AlertTransition{
-					Event: &smee.AlertTransitionEvent{
-						KeyMatches:   regexp.MustCompile("status-message"),
-						ValueMatches: regexp.MustCompile("NO ERRORS|Normal"),
-					},
-				},
-			},
-			"memory-usage": {
-				Create: smee.AlertTransition{
-					Event: &smee.AlertTransitionEvent{
-						KeyMatches:   regexp.MustCompile("^v-mem-used-percent$"),
-						ValueMatches: regexp.MustCompile("^([9][0-9]|[1-9][0-9]{2,})$"),
-					},
-				},
-				Close: smee.AlertTransition{
-					Event: &smee.AlertTransitionEvent{
-						KeyMatches:   regexp.MustCompile("^v-mem-used-percent$"),
-						ValueMatches: regexp.MustCompile(`^0*([0-9]|[1-8][0-9])\.`),
-					},
-				},
-			},
-			"shutter-error": {
-				Create: smee.AlertTransition{
-					Event: &smee.AlertTransitionEvent{
-						KeyMatches:   regexp.MustCompile("status-message"),
-						ValueMatches: regexp.MustCompile("SHUTTER ERROR"),
-					},
-				},
-				Close: smee.AlertTransition{
-					Event: &smee.AlertTransitionEvent{
-						KeyMatches:   regexp.MustCompile("status-message"),
-						ValueMatches: regexp.MustCompile("NO ERRORS"),
-					},
-				},
-			},
-			"touchpanel-offline": {
-				Create: smee.AlertTransition{
-					Event: &smee.AlertTransitionEvent{
-						KeyMatches:        regexp.MustCompile("^tp_online$"),
-						ValueDoesNotMatch: regexp.MustCompile("^Online$"),
-					},
-				},
-				Close: smee.AlertTransition{
-					Event: &smee.AlertTransitionEvent{
-						KeyMatches:   regexp.MustCompile("^tp_online$"),
-						ValueMatches: regexp.MustCompile("^Online$"),
-					},
-				},
-			},
-			/*
-				"interference": { // can't figure out how to do this one...
-					Create: smee.AlertTransition{
-						Event: &smee.AlertTransitionEvent{
-							KeyMatches:   regexp.MustCompile("interference"),
-							ValueMatches: regexp.MustCompile("Interference Detected"),
-						},
-					},
-					Close: smee.AlertTransition{
-						Event: &smee.AlertTransitionEvent{
-							KeyMatches:   regexp.MustCompile("^tp_online$"),
-							ValueMatches: regexp.MustCompile("^Online$"),
-						},
-					},
-				},
-			*/
-			"receiver": {
-				Create: smee.AlertTransition{
-					Event: &smee.AlertTransitionEvent{
-						KeyMatches:        regexp.MustCompile("mic-alerting"),
-						ValueDoesNotMatch: regexp.MustCompile("Okay"),
-					},
-				},
-				Close: smee.AlertTransition{
-					Event: &smee.AlertTransitionEvent{
-						KeyMatches:   regexp.MustCompile("mic-alerting"),
-						ValueMatches: regexp.MustCompile("Okay"),
-					},
-				},
-			},
-			"help-request": {
-				Create: smee.AlertTransition{
-					Event: &smee.AlertTransitionEvent{
-						KeyMatches:   regexp.MustCompile("help-request"),
-						ValueMatches: regexp.MustCompile("confirm"),
-					},
-				},
-				Close: smee.AlertTransition{},
-			},
-			"mic-battery 180 min": {
-				Create: smee.AlertTransition{
-					Event: &smee.AlertTransitionEvent{
-						KeyMatches:   regexp.MustCompile("battery-charge-minutes"),
-						ValueMatches: regexp.MustCompile("^0*(1[2-7][1-9]|1[3-8]0)$"),
-					},
-				},
-				Close: smee.AlertTransition{
-					Event: &smee.AlertTransitionEvent{
-						KeyMatches:   regexp.MustCompile("battery-charge-minutes"),
-						ValueMatches: regexp.MustCompile("^0*([1-9][0-9]{3,}|[2-9][0-9]{2,}|1[8-9][1-9]|190|1[0-1][0-9]|120|[0-9]{1,2})$"),
-					},
-				},
-			},
-			"mic-battery 120 min": {
-				Create: smee.AlertTransition{
-					Event: &smee.AlertTransitionEvent{
-						KeyMatches:   regexp.MustCompile("battery-charge-minutes"),
-						ValueMatches: regexp.MustCompile("^0*(9[1-9]|1[0-1][0-9]|120)$"),
-					},
-				},
-				Close: smee.AlertTransition{
-					Event: &smee.AlertTransitionEvent{
-						KeyMatches:   regexp.MustCompile("battery-charge-minutes"),
-						ValueMatches: regexp.MustCompile("^0*([1-9][0-9]{3,}|[2-9][0-9]{2,}|1[2-9][1-9]|1[3-9]0|[0-9]|[1-8][0-9]|90)$"),
-					},
-				},
-			},
-			"mic-battery 90 min": {
-				Create: smee.AlertTransition{
-					Event: &smee.AlertTransitionEvent{
-						KeyMatches:   regexp.MustCompile("battery-charge-minutes"),
-						ValueMatches: regexp.MustCompile("^0*([6-8][1-9]|[7-9]0)$"),
-					},
-				},
-				Close: smee.AlertTransition{
-					Event: &smee.AlertTransitionEvent{
-						KeyMatches:   regexp.MustCompile("battery-charge-minutes"),
-						ValueMatches: regexp.MustCompile("^0*([1-9][0-9]{2,}|9[1-9]|[0-9]|[1-5][0-9]|60)$"),
-					},
-				},
-			},
-			"mic-battery 60 min": {
-				Create: smee.AlertTransition{
-					Event: &smee.AlertTransitionEvent{
-						KeyMatches:   regexp.MustCompile("battery-charge-minutes"),
-						ValueMatches: regexp.MustCompile("^0*([3-5][1-9]|[4-6]0)$"),
-					},
-				},
-				Close: smee.AlertTransition{
-					Event: &smee.AlertTransitionEvent{
-						KeyMatches:   regexp.MustCompile("battery-charge-minutes"),
-						ValueMatches: regexp.MustCompile("^0*([1-9][0-9]{2,}|[6-9][1-9]|[7-9]0|[0-9]|[1-2][0-9]|30)$"),
-					},
-				},
-			},
-			"mic-battery 30 min": {
-				Create: smee.AlertTransition{
-					Event: &smee.AlertTransitionEvent{
-						KeyMatches:   regexp.MustCompile("battery-charge-minutes"),
-						ValueMatches: regexp.MustCompile("^0*([0-2][0-9]|[0-9]|30)$"),
-					},
-				},
-				Close: smee.AlertTransition{
-					Event: &smee.AlertTransitionEvent{
-						KeyMatches:   regexp.MustCompile("battery-charge-minutes"),
-						ValueMatches: regexp.MustCompile("^0*([1-9][0-9]{2,}|[4-9]0|[3-9][1-9])$"),
-					},
-				},
+		IssueStore:        d.issueStore,
+		MaintenanceStore:  d.maintenanceStore,
+		SilenceStore:      d.silenceStore,
+		EventStreamer:     d.eventStreamer,
+		DeviceStateStore:  d.deviceStateStore,
+		AlertConfigs:      d.alertConfig.AlertConfigs(),
+		StateAlertConfigs: d.alertConfig.StateAlertConfigs(),
+		Flapping:          d.alertConfig.FlapConfig(),
+		InhibitRules:      d.alertConfig.InhibitRules(),
+		SelfMonitorDevice: smee.Device{
+			ID: d.SelfMonitorDevice,
+			Room: smee.Room{
+				ID: d.SelfMonitorRoom,
 			},
 		},
+		StreamOutageAlertAfter: d.StreamOutageAlert,
+		StateSweepInterval:     d.StateSweepInterval,
+		ActionStore:            d.postgres,
+		ShadowStore:            d.shadowStore,
+		IncidentStore:          d.incidentStore,
+		Notifier:               d.notifier,
+		Escalations:            d.alertConfig.EscalationPolicies(),
+		IncidentRules:          d.alertConfig.IncidentRules(),
+		CorrelationRules:       d.alertConfig.CorrelationRules(),
+		BuildingRule:           d.alertConfig.BuildingRule(),
+		IssueTypeStore:         d.issuetypeStore,
+		ConfigWatcher: &config.Watcher{
+			Path:     d.AlertConfigFile,
+			Log:      d.log.Named("alert-config"),
+			OnReload: []func(config.Config){d.reloadNotifier},
+		},
 		Log: d.log.Named("alert-manager"),
 	}
 }
@@ -12,6 +12,7 @@ import (
 	"github.com/byuoitav/smee/internal/app/alertmanager/handlers"
 	"github.com/gin-gonic/gin"
 	adapter "github.com/gwatts/gin-adapter"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
 )
 
@@ -28,15 +29,21 @@ func (d *Deps) buildHTTPServer(ctx context.Context) {
 	d.handlers = &handlers.Handlers{
 		IssueStore:       d.issueStore,
 		MaintenanceStore: d.maintenanceStore,
+		SilenceStore:     d.silenceStore,
 		IncidentStore:    d.incidentStore,
 		IssueTypeStore:   d.issuetypeStore,
 		CouchManager:     *d.couchManager,
+		AlertManager:     d.alertManager,
+		ShadowStore:      d.shadowStore,
 	}
 
 	// build engine
 	r := gin.New()
 	r.Use(gin.Recovery())
 
+	// registered before the auth middleware so that it can be scraped
+	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
+
 	sessionStore := cookiestore.NewStore()
 
 	//auth
@@ -73,10 +80,21 @@ func (d *Deps) buildHTTPServer(ctx context.Context) {
 	api.PUT("/issues/:issueID/unacknowledgeIssue", d.handlers.UnacknowledgeIssue)
 	api.PUT("/issues/:issueID/setStatus", d.handlers.SetStatus)
 
+	api.POST("/alerts", d.handlers.CreateAlert)
+	api.POST("/alerts/close", d.handlers.CloseAlert)
+	api.GET("/alerts/pending", d.handlers.PendingAlerts)
+	api.GET("/alerts/shadow", d.handlers.ShadowAlerts)
+
 	api.GET("/maintenance", d.handlers.RoomsInMaintenance)
 	api.GET("/maintenance/:roomID", d.handlers.RoomMaintenanceInfo)
 	api.PUT("/maintenance/:roomID", d.handlers.SetMaintenanceInfo)
 
+	api.GET("/silences", d.handlers.Silences)
+	api.POST("/silences", d.handlers.CreateSilence)
+	api.GET("/silences/:silenceID", d.handlers.Silence)
+	api.PUT("/silences/:silenceID", d.handlers.UpdateSilence)
+	api.DELETE("/silences/:silenceID", d.handlers.DeleteSilence)
+
 	api.GET("/rooms", d.handlers.Rooms)
 	api.GET("/issuetype", d.handlers.SNIssueType)
 
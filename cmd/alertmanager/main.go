@@ -4,11 +4,16 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"os"
+	"time"
 
 	"github.com/byuoitav/auth/wso2"
+	"github.com/byuoitav/smee/internal/app/alertmanager/config"
 	"github.com/byuoitav/smee/internal/app/alertmanager/handlers"
+	"github.com/byuoitav/smee/internal/app/alertmanager/notify"
 	"github.com/byuoitav/smee/internal/app/commandcli"
 	"github.com/byuoitav/smee/internal/pkg/couch"
+	"github.com/byuoitav/smee/internal/pkg/eventlog"
 	"github.com/byuoitav/smee/internal/pkg/postgres"
 	"github.com/byuoitav/smee/internal/smee"
 	"github.com/byuoitav/smee/opa"
@@ -38,6 +43,14 @@ type Deps struct {
 	CouchUsername        string
 	CouchPassword        string
 	WebRoot              string
+	AlertConfigFile      string
+	SelfMonitorRoom      string
+	SelfMonitorDevice    string
+	StreamOutageAlert    time.Duration
+	StateSweepInterval   time.Duration
+	RecordEventsFile     string
+	ReplayEventsFile     string
+	ReplaySpeed          float64
 
 	// created by functions
 	log              *zap.Logger
@@ -48,9 +61,14 @@ type Deps struct {
 	issueStore       smee.IssueStore
 	incidentStore    smee.IncidentStore
 	maintenanceStore smee.MaintenanceStore
+	silenceStore     smee.SilenceStore
 	issuetypeStore   smee.IssueTypeStore
+	alertConfig      config.Config
+	notifier         *notify.Router
 	alertManager     smee.AlertManager
+	shadowStore      smee.ShadowStore
 	eventStreamer    smee.EventStreamer
+	eventRecorder    *eventlog.Recorder
 	deviceStateStore smee.DeviceStateStore
 	commandClient    *commandcli.Client
 	couchManager     *couch.CouchManager
@@ -61,6 +79,10 @@ type Deps struct {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "test-rules" {
+		os.Exit(testRules(os.Args[2:]))
+	}
+
 	var deps Deps
 
 	pflag.IntVarP(&deps.Port, "port", "P", 8080, "port to run the server on")
@@ -82,6 +104,14 @@ func main() {
 	pflag.StringVar(&deps.CouchUsername, "couch-username", "", "")
 	pflag.StringVar(&deps.CouchPassword, "couch-password", "", "")
 	pflag.StringVar(&deps.WebRoot, "web-root", "/website", "The location on the filesystem of the root of the website files")
+	pflag.StringVar(&deps.AlertConfigFile, "alert-config", "/alerts.yaml", "path to the alert config file (yaml or json). reloaded on SIGHUP or when it changes")
+	pflag.StringVar(&deps.SelfMonitorRoom, "self-monitor-room", "SMEE-ALERTS", "room that alerts about the alert manager itself are created in")
+	pflag.StringVar(&deps.SelfMonitorDevice, "self-monitor-device", "SMEE-ALERTS-SVC1", "device that alerts about the alert manager itself are created on. empty disables them")
+	pflag.DurationVar(&deps.StreamOutageAlert, "stream-outage-alert-after", 5*time.Minute, "how long the event stream can be down before an alert is created")
+	pflag.DurationVar(&deps.StateSweepInterval, "state-sweep-interval", 5*time.Minute, "how often every device in redis is run through the state queries, to catch changes the keyspace notifications missed")
+	pflag.StringVar(&deps.RecordEventsFile, "record-events", "", "append every event from the hub to this file (json lines)")
+	pflag.StringVar(&deps.ReplayEventsFile, "replay-events", "", "replay events from a file written by --record-events instead of connecting to the hub")
+	pflag.Float64Var(&deps.ReplaySpeed, "replay-speed", 1, "how many times faster than real time to replay events. 0 replays them as fast as possible")
 	pflag.Parse()
 
 	deps.build()
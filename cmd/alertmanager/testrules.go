@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/byuoitav/smee/internal/app/alertmanager/config"
+	"github.com/byuoitav/smee/internal/app/alertmanager/redis"
+	"github.com/byuoitav/smee/internal/app/alertmanager/ruletest"
+	"github.com/byuoitav/smee/internal/pkg/eventlog"
+	"github.com/spf13/pflag"
+)
+
+// testRules runs the test-rules subcommand and returns the exit code. It
+// runs a file of recorded events (see --record-events) through the alert
+// config and device state queries with a fake clock, and prints when each
+// alert would have opened and closed.
+func testRules(args []string) int {
+	flags := pflag.NewFlagSet("test-rules", pflag.ContinueOnError)
+
+	configFile := flags.String("alert-config", "alerts.yaml", "path to the alert config file")
+	eventsFile := flags.String("events", "", "file of recorded events to run through the rules (required)")
+	golden := flags.String("golden", "", "compare the timeline to this file, and fail if it is different")
+	update := flags.Bool("update", false, "write the timeline to the --golden file instead of comparing it")
+	tail := flags.Duration("tail", 10*time.Minute, "how long to keep the clock running after the last event")
+	noState := flags.Bool("no-state-queries", false, "only run the event alerts")
+
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: alertmanager test-rules --events <file> [flags]\n\n")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *eventsFile == "" || (*update && *golden == "") {
+		flags.Usage()
+		return 2
+	}
+
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid alert config: %s\n", err)
+		return 1
+	}
+
+	events, err := os.Open(*eventsFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to open events: %s\n", err)
+		return 1
+	}
+	defer events.Close()
+
+	eval := &ruletest.Evaluator{
+		AlertConfigs:      cfg.AlertConfigs(),
+		StateAlertConfigs: cfg.StateAlertConfigs(),
+	}
+
+	if !*noState {
+		eval.State = redis.NewSimulator()
+	}
+
+	transitions, err := eval.Run(eventlog.NewReader(events), *tail)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to run events: %s\n", err)
+		return 1
+	}
+
+	var timeline bytes.Buffer
+	if err := ruletest.WriteTimeline(&timeline, transitions, eval.Open()); err != nil {
+		fmt.Fprintf(os.Stderr, "unable to write timeline: %s\n", err)
+		return 1
+	}
+
+	switch {
+	case *update:
+		if err := ioutil.WriteFile(*golden, timeline.Bytes(), 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "unable to write golden file: %s\n", err)
+			return 1
+		}
+
+		fmt.Printf("wrote %s\n", *golden)
+	case *golden != "":
+		want, err := ioutil.ReadFile(*golden)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "unable to read golden file: %s\n", err)
+			return 1
+		}
+
+		if diff := ruletest.Diff(want, timeline.Bytes()); diff != "" {
+			fmt.Printf("timeline does not match %s (-want +got):\n%s", *golden, diff)
+			return 1
+		}
+
+		fmt.Printf("timeline matches %s\n", *golden)
+	default:
+		os.Stdout.Write(timeline.Bytes()) // nolint:errcheck
+	}
+
+	return 0
+}
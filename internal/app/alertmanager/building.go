@@ -0,0 +1,359 @@
+package alertmanager
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/byuoitav/smee/internal/smee"
+	"go.uber.org/zap"
+)
+
+func (m *Manager) buildingRule() smee.BuildingRule {
+	m.configMu.RLock()
+	defer m.configMu.RUnlock()
+	return m.BuildingRule
+}
+
+// correlateBuilding links issue to its building's outage issue. If the
+// building doesn't have one, it is created once enough rooms in the building
+// have new issues at the same time. Building issues are created with an
+// alert on a device named after the building, in a room named after it.
+func (m *Manager) correlateBuilding(ctx context.Context, issue smee.Issue, alert smee.Alert) {
+	rule := m.buildingRule()
+	if rule.MinRooms == 0 || alert.Type == rule.Type {
+		return
+	}
+
+	building := rule.Building(issue.Room.ID)
+	if building == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	var parentID string
+	for _, a := range m.active.device(building, building) {
+		if a.Type == rule.Type {
+			parentID = a.IssueID
+		}
+	}
+
+	var rooms []issueSummary
+	for _, iss := range m.buildings.building(building) {
+		switch {
+		case iss.roomID == building, m.buildings.active(iss.parentID):
+			// the building issue, or already linked
+		case absDuration(issue.Start.Sub(iss.start)) <= rule.Within:
+			rooms = append(rooms, iss)
+		}
+	}
+
+	if len(rooms) == 0 {
+		return
+	}
+
+	if parentID == "" {
+		if len(rooms) < rule.MinRooms {
+			return
+		}
+
+		parent, ok := m.createBuildingIssue(ctx, rule, building, len(rooms))
+		if !ok {
+			return
+		}
+
+		parentID = parent.ID
+	}
+
+	var ids, names []string
+	for _, room := range rooms {
+		ids = append(ids, room.id)
+		names = append(names, room.roomID)
+	}
+
+	sort.Strings(names)
+
+	if err := m.IssueStore.SetIssueParent(ctx, parentID, ids...); err != nil {
+		m.Log.Error("unable to link room issues to building issue", zap.Error(err), zap.String("building", building), zap.String("parentID", parentID), zap.Strings("issueIDs", ids))
+		return
+	}
+
+	m.buildings.setParent(parentID, ids...)
+	m.Log.Info("Linked room issues to building issue", zap.String("building", building), zap.String("parentID", parentID), zap.Strings("rooms", names))
+
+	event := smee.IssueEvent{
+		Type:      smee.TypeSystemMessage,
+		Timestamp: time.Now(),
+		Data:      smee.NewSystemMessage(fmt.Sprintf("AV Bot: linked %v", strings.Join(names, ", "))),
+	}
+
+	if err := m.IssueStore.AddIssueEvents(ctx, parentID, event); err != nil {
+		m.Log.Error("unable to add issue events", zap.Error(err), zap.String("issueID", parentID))
+	}
+}
+
+// createBuildingIssue creates the building issue for building, returning it
+func (m *Manager) createBuildingIssue(ctx context.Context, rule smee.BuildingRule, building string, rooms int) (smee.Issue, bool) {
+	alert := smee.Alert{
+		Device: smee.Device{
+			ID: building,
+			Room: smee.Room{
+				ID: building,
+			},
+		},
+		Type:  rule.Type,
+		Start: time.Now(),
+	}
+
+	if s, ok := m.silencedBy(ctx, alert); ok {
+		m.Log.Debug("Not creating silenced building issue", zap.String("building", building), zap.String("type", rule.Type), zap.String("silenceID", s.ID))
+		return smee.Issue{}, false
+	}
+
+	events := []smee.IssueEvent{
+		{
+			Type:      smee.TypeSystemMessage,
+			Timestamp: alert.Start,
+			Data:      smee.NewSystemMessage(fmt.Sprintf("AV Bot: %v rooms in %v had issues within %v of each other, probable %v", rooms, building, rule.Within, rule.Type)),
+		},
+	}
+
+	issue, ok := m.createAlert(ctx, alert, events)
+	if !ok {
+		return smee.Issue{}, false
+	}
+
+	for _, a := range issue.Alerts {
+		if a.Active() && a.Type == rule.Type {
+			m.queueAlertIncident(issue, a)
+			break
+		}
+	}
+
+	return issue, true
+}
+
+// closeBuildingIssue closes the building issue issue was linked to, once
+// none of the room issues linked to it are active. issue was just closed.
+func (m *Manager) closeBuildingIssue(ctx context.Context, issue smee.Issue) {
+	if issue.Active() || issue.ParentID == "" {
+		return
+	}
+
+	if m.buildings.hasChildren(issue.ParentID) {
+		return
+	}
+
+	parent, ok := m.buildings.issue(issue.ParentID)
+	if !ok {
+		return
+	}
+
+	for _, a := range m.active.device(parent.roomID, parent.roomID) {
+		if a.IssueID != parent.id {
+			continue
+		}
+
+		m.closeAlert(ctx, a, []smee.IssueEvent{
+			{
+				Type:      smee.TypeSystemMessage,
+				Timestamp: time.Now(),
+				Data:      smee.NewSystemMessage(fmt.Sprintf("AV Bot: every room in the %v has recovered", a.Type)),
+			},
+		})
+	}
+}
+
+// issueSummary is what building outages need to know about an active issue
+type issueSummary struct {
+	id       string
+	roomID   string
+	parentID string
+	start    time.Time
+}
+
+// buildingIndex is the set of active issues, indexed by building, so that
+// building outages don't have to go through every active issue. Like
+// alertIndex, it is only written to by runAlertActions.
+type buildingIndex struct {
+	mu sync.RWMutex
+
+	// rule decides which building an issue's room is in
+	rule smee.BuildingRule
+
+	// issues is a map of issueID -> active issue
+	issues map[string]issueSummary
+
+	// buildings is a map of building -> issueID -> true
+	buildings map[string]map[string]bool
+}
+
+// reset replaces every issue in the index with issues, grouping them into
+// buildings with rule
+func (idx *buildingIndex) reset(rule smee.BuildingRule, issues []issueSummary) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.rule = rule
+	idx.issues = make(map[string]issueSummary, len(issues))
+	idx.buildings = make(map[string]map[string]bool)
+
+	for _, iss := range issues {
+		idx.add(iss)
+	}
+}
+
+// regroup groups the issues in the index into buildings with rule
+func (idx *buildingIndex) regroup(rule smee.BuildingRule) {
+	idx.mu.RLock()
+	issues := make([]issueSummary, 0, len(idx.issues))
+	for _, iss := range idx.issues {
+		issues = append(issues, iss)
+	}
+	idx.mu.RUnlock()
+
+	idx.reset(rule, issues)
+}
+
+// update adds issue to the index, or removes it if it is no longer active
+func (idx *buildingIndex) update(issue smee.Issue) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.remove(issue.ID)
+	if issue.Active() {
+		idx.add(summarize(issue))
+	}
+}
+
+// setParent marks each of issueIDs as linked to parentID
+func (idx *buildingIndex) setParent(parentID string, issueIDs ...string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	for _, id := range issueIDs {
+		if iss, ok := idx.issues[id]; ok {
+			iss.parentID = parentID
+			idx.issues[id] = iss
+		}
+	}
+}
+
+// add assumes mu is locked
+func (idx *buildingIndex) add(iss issueSummary) {
+	if idx.issues == nil {
+		idx.issues = make(map[string]issueSummary)
+		idx.buildings = make(map[string]map[string]bool)
+	}
+
+	idx.issues[iss.id] = iss
+
+	building := idx.rule.Building(iss.roomID)
+	if building == "" {
+		return
+	}
+
+	if idx.buildings[building] == nil {
+		idx.buildings[building] = make(map[string]bool)
+	}
+
+	idx.buildings[building][iss.id] = true
+}
+
+// remove assumes mu is locked
+func (idx *buildingIndex) remove(issueID string) {
+	iss, ok := idx.issues[issueID]
+	if !ok {
+		return
+	}
+
+	delete(idx.issues, issueID)
+
+	building := idx.rule.Building(iss.roomID)
+	delete(idx.buildings[building], issueID)
+	if len(idx.buildings[building]) == 0 {
+		delete(idx.buildings, building)
+	}
+}
+
+// building returns the active issues in building
+func (idx *buildingIndex) building(building string) []issueSummary {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var res []issueSummary
+	for id := range idx.buildings[building] {
+		res = append(res, idx.issues[id])
+	}
+
+	return res
+}
+
+// issue returns the active issue with issueID
+func (idx *buildingIndex) issue(issueID string) (issueSummary, bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	iss, ok := idx.issues[issueID]
+	return iss, ok
+}
+
+// active returns true if issueID is an active issue
+func (idx *buildingIndex) active(issueID string) bool {
+	_, ok := idx.issue(issueID)
+	return ok
+}
+
+// hasChildren returns true if any active issue is linked to parentID
+func (idx *buildingIndex) hasChildren(parentID string) bool {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	for _, iss := range idx.issues {
+		if iss.parentID == parentID {
+			return true
+		}
+	}
+
+	return false
+}
+
+func summarize(issue smee.Issue) issueSummary {
+	return issueSummary{
+		id:       issue.ID,
+		roomID:   issue.Room.ID,
+		parentID: issue.ParentID,
+		start:    issue.Start,
+	}
+}
+
+// syncBuildingIssues rebuilds the building index from the issue store. Like
+// syncActiveAlerts, this picks up issues that were changed outside of the
+// manager.
+func (m *Manager) syncBuildingIssues(ctx context.Context) {
+	rule := m.buildingRule()
+	if rule.MinRooms == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	issues, err := m.IssueStore.ActiveIssues(ctx)
+	if err != nil {
+		m.Log.Error("unable to sync building issues", zap.Error(err))
+		return
+	}
+
+	summaries := make([]issueSummary, 0, len(issues))
+	for _, issue := range issues {
+		summaries = append(summaries, summarize(issue))
+	}
+
+	m.buildings.reset(rule, summaries)
+}
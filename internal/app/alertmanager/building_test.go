@@ -0,0 +1,51 @@
+package alertmanager
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/byuoitav/smee/internal/smee"
+	"github.com/matryer/is"
+)
+
+func TestBuildingOutage(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	m, issues := newTestManager(nil)
+	m.BuildingRule = smee.BuildingRule{
+		Type:     "building-outage",
+		MinRooms: 3,
+		Within:   5 * time.Minute,
+	}
+
+	rooms := []string{"ITB-1101", "ITB-1102", "ITB-1103", "ITB-1104"}
+	for _, room := range rooms {
+		m.runAlertAction(ctx, createAction(room, room+"-CP1", "device-offline"))
+	}
+
+	// rooms in other buildings aren't linked
+	m.runAlertAction(ctx, createAction("JFSB-B120", "JFSB-B120-CP1", "device-offline"))
+
+	is.Equal(issues.created["building-outage"], 1)
+
+	outage := m.active.device("ITB", "ITB")
+	is.Equal(len(outage), 1)
+
+	for _, room := range rooms {
+		issue, err := issues.ActiveIssue(ctx, room)
+		is.NoErr(err)
+		is.Equal(issue.ParentID, outage[0].IssueID) // every room, including ones after the outage opened
+	}
+
+	issue, err := issues.ActiveIssue(ctx, "JFSB-B120")
+	is.NoErr(err)
+	is.Equal(issue.ParentID, "")
+
+	// the building issue closes once every room has recovered
+	for i, room := range rooms {
+		closeActive(ctx, m, room, room+"-CP1", "device-offline")
+		is.Equal(m.active.contains(outage[0]), i < len(rooms)-1)
+	}
+}
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/byuoitav/smee/internal/smee"
+)
+
+// DefaultBuildingOutageType is the alert type of building issues that don't set a type
+const DefaultBuildingOutageType = "building-outage"
+
+// BuildingOutage raises a building issue when MinRooms rooms in a building
+// get new issues within Within of each other
+type BuildingOutage struct {
+	// Type is the alert type of the building issue's alert
+	Type     string   `yaml:"type"`
+	MinRooms int      `yaml:"minRooms"`
+	Within   Duration `yaml:"within"`
+
+	// Groups is a map of building -> room ID prefixes, for rooms whose
+	// building isn't the part of their ID before the first "-"
+	Groups map[string][]string `yaml:"groups"`
+}
+
+func (c Config) validateBuildingOutage() error {
+	b := c.BuildingOutage
+	if b == nil {
+		return nil
+	}
+
+	typ := b.alertType()
+	if _, ok := c.Alerts[typ]; ok {
+		return fmt.Errorf("buildingOutage.type: %s is already an alert type", typ)
+	}
+
+	if _, ok := c.StateAlerts[typ]; ok {
+		return fmt.Errorf("buildingOutage.type: %s is already a state alert type", typ)
+	}
+
+	if _, ok := c.Correlations[typ]; ok {
+		return fmt.Errorf("buildingOutage.type: %s is already a correlation type", typ)
+	}
+
+	if err := b.validate(); err != nil {
+		return fmt.Errorf("buildingOutage.%w", err)
+	}
+
+	return nil
+}
+
+func (b BuildingOutage) validate() error {
+	switch {
+	case b.MinRooms < 2:
+		return errors.New("minRooms: must be at least 2")
+	case b.Within <= 0:
+		return errors.New("within: is required")
+	}
+
+	for building, prefixes := range b.Groups {
+		if len(prefixes) == 0 {
+			return fmt.Errorf("groups.%s: at least one room prefix is required", building)
+		}
+
+		for _, prefix := range prefixes {
+			if prefix == "" {
+				return fmt.Errorf("groups.%s: room prefixes must not be empty", building)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (b BuildingOutage) alertType() string {
+	if b.Type == "" {
+		return DefaultBuildingOutageType
+	}
+
+	return b.Type
+}
+
+// BuildingRule converts the building outage config into a smee.BuildingRule.
+// The zero rule is returned if building outages aren't configured.
+func (c Config) BuildingRule() smee.BuildingRule {
+	if c.BuildingOutage == nil {
+		return smee.BuildingRule{}
+	}
+
+	return smee.BuildingRule{
+		Type:     c.BuildingOutage.alertType(),
+		MinRooms: c.BuildingOutage.MinRooms,
+		Within:   time.Duration(c.BuildingOutage.Within),
+		Groups:   c.BuildingOutage.Groups,
+	}
+}
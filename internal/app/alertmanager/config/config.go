@@ -0,0 +1,500 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/byuoitav/smee/internal/pkg/expr"
+	"github.com/byuoitav/smee/internal/smee"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the declarative configuration for the alert manager. It is
+// loaded from a YAML (or JSON) file.
+type Config struct {
+	// Alerts is a map of alert type -> how to create/close alerts of that type
+	Alerts map[string]Alert `yaml:"alerts"`
+
+	// StateAlerts is a map of device state query name -> how to handle the alerts it creates
+	StateAlerts map[string]StateAlert `yaml:"stateAlerts"`
+
+	// Flapping is the default flap detection for every alert type
+	Flapping *Flapping `yaml:"flapping"`
+
+	// Inhibitions keep symptoms of an active alert from being created in the same room
+	Inhibitions []Inhibition `yaml:"inhibitions"`
+
+	// Notifiers is a map of name -> where to send notifications
+	Notifiers map[string]Notifier `yaml:"notifiers"`
+
+	// Notifications route issue lifecycle events to Notifiers
+	Notifications []NotificationRoute `yaml:"notifications"`
+
+	// Escalations is a map of policy name -> how to escalate unacknowledged issues
+	Escalations map[string]Escalation `yaml:"escalations"`
+
+	// Incidents is a map of alert type -> incident to open when an alert of that type is created
+	Incidents map[string]Incident `yaml:"incidents"`
+
+	// Correlations is a map of alert type -> when to open a single alert of
+	// that type for a room with many failing devices
+	Correlations map[string]Correlation `yaml:"correlations"`
+
+	// BuildingOutage raises one issue for a building when many of its rooms get issues together
+	BuildingOutage *BuildingOutage `yaml:"buildingOutage"`
+}
+
+type Inhibition struct {
+	// Source is the alert type that, while active in a room, inhibits Targets in that room
+	Source  string   `yaml:"source"`
+	Targets []string `yaml:"targets"`
+}
+
+type Alert struct {
+	Create Transition `yaml:"create"`
+	Close  Transition `yaml:"close"`
+
+	// For is how long the create transition has to hold before the alert is created
+	For Duration `yaml:"for"`
+
+	Flapping *Flapping `yaml:"flapping"`
+
+	// TTL closes alerts that have been open longer than TTL
+	TTL Duration `yaml:"ttl"`
+
+	// Shadow evaluates the alert without opening issues
+	Shadow bool `yaml:"shadow"`
+}
+
+type StateAlert struct {
+	// Query selects the devices that should have an alert
+	Query *Query `yaml:"query"`
+
+	// Interval is how often every device is run through Query
+	Interval Duration `yaml:"interval"`
+
+	// Thresholds are the values of Query's parameters
+	Thresholds map[string]string `yaml:"thresholds"`
+
+	// Overrides change Thresholds for some devices. The first override
+	// that matches a device is used.
+	Overrides []StateOverride `yaml:"overrides"`
+
+	// ForScans is how many consecutive state query runs have to match before the alert is created
+	ForScans int `yaml:"forScans"`
+
+	Flapping *Flapping `yaml:"flapping"`
+
+	// Shadow evaluates the query's alerts without opening issues
+	Shadow bool `yaml:"shadow"`
+}
+
+// Flapping is flap detection for an alert. An alert that opens/closes
+// Transitions times within Window is held open until it settles.
+type Flapping struct {
+	Transitions int      `yaml:"transitions"`
+	Window      Duration `yaml:"window"`
+}
+
+type Transition struct {
+	Event *EventTransition `yaml:"event"`
+
+	// Hysteresis is only valid on a close transition without an event. The
+	// close transition is derived from the create transition's value
+	// condition, offset by Hysteresis (e.g. create at gt 80 with a hysteresis
+	// of 10 closes at lt 70).
+	Hysteresis *float64 `yaml:"hysteresis"`
+}
+
+type EventTransition struct {
+	KeyMatches        *Regexp `yaml:"keyMatches"`
+	KeyDoesNotMatch   *Regexp `yaml:"keyDoesNotMatch"`
+	ValueMatches      *Regexp `yaml:"valueMatches"`
+	ValueDoesNotMatch *Regexp `yaml:"valueDoesNotMatch"`
+
+	// Value compares the event's value as a number
+	Value *NumericCondition `yaml:"value"`
+}
+
+// NumericCondition must have exactly one of its fields set.
+type NumericCondition struct {
+	GreaterThan *float64  `yaml:"gt"`
+	LessThan    *float64  `yaml:"lt"`
+	AtLeast     *float64  `yaml:"gte"`
+	AtMost      *float64  `yaml:"lte"`
+	Between     []float64 `yaml:"between"`
+}
+
+// Regexp is a regular expression that is compiled when it is unmarshaled,
+// so that invalid expressions are reported with their location in the file.
+type Regexp struct {
+	*regexp.Regexp
+}
+
+func (r *Regexp) UnmarshalYAML(node *yaml.Node) error {
+	var expr string
+	if err := node.Decode(&expr); err != nil {
+		return err
+	}
+
+	reg, err := regexp.Compile(expr)
+	if err != nil {
+		return fmt.Errorf("line %d, column %d: invalid regular expression %q: %w", node.Line, node.Column, expr, err)
+	}
+
+	r.Regexp = reg
+	return nil
+}
+
+// Query is a device state query written in the expr language. It is parsed
+// when it is unmarshaled, so that invalid queries are reported with their
+// location in the file.
+type Query struct {
+	*expr.Expr
+}
+
+func (q *Query) UnmarshalYAML(node *yaml.Node) error {
+	var src string
+	if err := node.Decode(&src); err != nil {
+		return err
+	}
+
+	e, err := expr.Parse(src)
+	if err != nil {
+		return fmt.Errorf("line %d, column %d: invalid query %q: %w", node.Line, node.Column, src, err)
+	}
+
+	q.Expr = e
+	return nil
+}
+
+// Duration is a time.Duration written as a string, like "90s" or "5m".
+type Duration time.Duration
+
+func (d *Duration) UnmarshalYAML(node *yaml.Node) error {
+	var str string
+	if err := node.Decode(&str); err != nil {
+		return err
+	}
+
+	dur, err := time.ParseDuration(str)
+	if err != nil {
+		return fmt.Errorf("line %d, column %d: invalid duration %q: %w", node.Line, node.Column, str, err)
+	}
+
+	*d = Duration(dur)
+	return nil
+}
+
+// Load reads and validates the config file at path.
+func Load(path string) (Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("unable to read config: %w", err)
+	}
+
+	cfg, err := Parse(data)
+	if err != nil {
+		return Config{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return cfg, nil
+}
+
+// Parse parses and validates a config. Unknown fields are treated as errors
+// so that typos don't silently disable part of an alert.
+func Parse(data []byte) (Config, error) {
+	var cfg Config
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+
+	if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return Config{}, err
+	}
+
+	if err := cfg.validate(); err != nil {
+		return Config{}, err
+	}
+
+	return cfg, nil
+}
+
+func (c Config) validate() error {
+	if len(c.Alerts) == 0 {
+		return errors.New("no alerts are configured")
+	}
+
+	for _, typ := range c.alertTypes() {
+		if err := c.Alerts[typ].validate(); err != nil {
+			return fmt.Errorf("alerts.%s.%w", typ, err)
+		}
+	}
+
+	for typ, state := range c.StateAlerts {
+		if err := state.validate(); err != nil {
+			return fmt.Errorf("stateAlerts.%s.%w", typ, err)
+		}
+	}
+
+	if err := c.Flapping.validate(); err != nil {
+		return fmt.Errorf("flapping: %w", err)
+	}
+
+	for i, inhibition := range c.Inhibitions {
+		switch {
+		case inhibition.Source == "":
+			return fmt.Errorf("inhibitions[%d].source: is required", i)
+		case len(inhibition.Targets) == 0:
+			return fmt.Errorf("inhibitions[%d].targets: at least one target is required", i)
+		}
+
+		for _, target := range inhibition.Targets {
+			if target == inhibition.Source {
+				return fmt.Errorf("inhibitions[%d].targets: %s can't inhibit itself", i, target)
+			}
+		}
+	}
+
+	if err := c.validateNotifications(); err != nil {
+		return err
+	}
+
+	if err := c.validateEscalations(); err != nil {
+		return err
+	}
+
+	if err := c.validateIncidents(); err != nil {
+		return err
+	}
+
+	if err := c.validateCorrelations(); err != nil {
+		return err
+	}
+
+	return c.validateBuildingOutage()
+}
+
+func (f *Flapping) validate() error {
+	switch {
+	case f == nil:
+		return nil
+	case f.Transitions < 0:
+		return errors.New("transitions must not be negative")
+	case f.Transitions > 0 && f.Window <= 0:
+		return errors.New("window is required")
+	}
+
+	return nil
+}
+
+func (a Alert) validate() error {
+	switch {
+	case a.Create.Event == nil:
+		return errors.New("create: an event transition is required")
+	case a.Create.Hysteresis != nil:
+		return errors.New("create.hysteresis: hysteresis is only valid on close")
+	case a.Close.Hysteresis != nil && a.Close.Event != nil:
+		return errors.New("close: hysteresis and event are mutually exclusive")
+	case a.Close.Hysteresis != nil && a.Create.Event.Value == nil:
+		return errors.New("close.hysteresis: create.event.value is required to use hysteresis")
+	case a.Close.Hysteresis != nil && *a.Close.Hysteresis < 0:
+		return errors.New("close.hysteresis: must not be negative")
+	case a.For < 0:
+		return errors.New("for: must not be negative")
+	case a.TTL < 0:
+		return errors.New("ttl: must not be negative")
+	}
+
+	if err := a.Flapping.validate(); err != nil {
+		return fmt.Errorf("flapping: %w", err)
+	}
+
+	if err := a.Create.Event.Value.validate(); err != nil {
+		return fmt.Errorf("create.event.value: %w", err)
+	}
+
+	if a.Close.Event != nil {
+		if err := a.Close.Event.Value.validate(); err != nil {
+			return fmt.Errorf("close.event.value: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (n *NumericCondition) validate() error {
+	if n == nil {
+		return nil
+	}
+
+	set := 0
+	if n.GreaterThan != nil {
+		set++
+	}
+
+	if n.LessThan != nil {
+		set++
+	}
+
+	if n.AtLeast != nil {
+		set++
+	}
+
+	if n.AtMost != nil {
+		set++
+	}
+
+	if n.Between != nil {
+		set++
+
+		switch {
+		case len(n.Between) != 2:
+			return errors.New("between must be [low, high]")
+		case n.Between[0] > n.Between[1]:
+			return errors.New("between: low must not be greater than high")
+		}
+	}
+
+	if set != 1 {
+		return errors.New("exactly one of gt, lt, gte, lte, or between is required")
+	}
+
+	return nil
+}
+
+// alertTypes returns the configured alert types in a stable order.
+func (c Config) alertTypes() []string {
+	var types []string
+	for typ := range c.Alerts {
+		types = append(types, typ)
+	}
+
+	sort.Strings(types)
+	return types
+}
+
+// AlertConfigs converts the configured alerts into smee.AlertConfigs.
+func (c Config) AlertConfigs() map[string]smee.AlertConfig {
+	configs := make(map[string]smee.AlertConfig, len(c.Alerts))
+	for typ, alert := range c.Alerts {
+		create := alert.Create.convert()
+
+		closing := alert.Close.convert()
+		if alert.Close.Hysteresis != nil {
+			// same key/value filters as create, opposite side of the band
+			event := *create.Event
+			cond := event.Value.Hysteresis(*alert.Close.Hysteresis)
+			event.Value = &cond
+
+			closing = smee.AlertTransition{Event: &event}
+		}
+
+		configs[typ] = smee.AlertConfig{
+			Create:   create,
+			Close:    closing,
+			For:      time.Duration(alert.For),
+			Flapping: alert.Flapping.convert(),
+			TTL:      time.Duration(alert.TTL),
+			Shadow:   alert.Shadow,
+		}
+	}
+
+	return configs
+}
+
+// StateAlertConfigs converts the configured state alerts into smee.StateAlertConfigs.
+func (c Config) StateAlertConfigs() map[string]smee.StateAlertConfig {
+	configs := make(map[string]smee.StateAlertConfig, len(c.StateAlerts))
+	for typ, state := range c.StateAlerts {
+		configs[typ] = smee.StateAlertConfig{
+			Query:    state.query(),
+			Interval: time.Duration(state.Interval),
+			ForScans: state.ForScans,
+			Flapping: state.Flapping.convert(),
+			Shadow:   state.Shadow,
+		}
+	}
+
+	return configs
+}
+
+// InhibitRules converts the configured inhibitions into smee.InhibitRules.
+func (c Config) InhibitRules() []smee.InhibitRule {
+	var rules []smee.InhibitRule
+	for _, inhibition := range c.Inhibitions {
+		rules = append(rules, smee.InhibitRule{
+			Source:  inhibition.Source,
+			Targets: inhibition.Targets,
+		})
+	}
+
+	return rules
+}
+
+// FlapConfig returns the default flap detection config.
+func (c Config) FlapConfig() smee.FlapConfig {
+	if c.Flapping == nil {
+		return smee.FlapConfig{}
+	}
+
+	return *c.Flapping.convert()
+}
+
+func (f *Flapping) convert() *smee.FlapConfig {
+	if f == nil {
+		return nil
+	}
+
+	return &smee.FlapConfig{
+		Transitions: f.Transitions,
+		Window:      time.Duration(f.Window),
+	}
+}
+
+func (t Transition) convert() smee.AlertTransition {
+	if t.Event == nil {
+		return smee.AlertTransition{}
+	}
+
+	return smee.AlertTransition{
+		Event: &smee.AlertTransitionEvent{
+			KeyMatches:        t.Event.KeyMatches.regexp(),
+			KeyDoesNotMatch:   t.Event.KeyDoesNotMatch.regexp(),
+			ValueMatches:      t.Event.ValueMatches.regexp(),
+			ValueDoesNotMatch: t.Event.ValueDoesNotMatch.regexp(),
+			Value:             t.Event.Value.convert(),
+		},
+	}
+}
+
+func (n *NumericCondition) convert() *smee.NumericCondition {
+	switch {
+	case n == nil:
+		return nil
+	case n.GreaterThan != nil:
+		return &smee.NumericCondition{Op: smee.NumericGreaterThan, Threshold: *n.GreaterThan}
+	case n.LessThan != nil:
+		return &smee.NumericCondition{Op: smee.NumericLessThan, Threshold: *n.LessThan}
+	case n.AtLeast != nil:
+		return &smee.NumericCondition{Op: smee.NumericAtLeast, Threshold: *n.AtLeast}
+	case n.AtMost != nil:
+		return &smee.NumericCondition{Op: smee.NumericAtMost, Threshold: *n.AtMost}
+	default:
+		return &smee.NumericCondition{Op: smee.NumericBetween, Low: n.Between[0], High: n.Between[1]}
+	}
+}
+
+func (r *Regexp) regexp() *regexp.Regexp {
+	if r == nil {
+		return nil
+	}
+
+	return r.Regexp
+}
@@ -0,0 +1,461 @@
+package config
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/byuoitav/smee/internal/smee"
+	"github.com/matryer/is"
+)
+
+func TestLoadDefaultConfig(t *testing.T) {
+	is := is.New(t)
+
+	cfg, err := Load("../../../../alerts.yaml")
+	is.NoErr(err)
+
+	configs := cfg.AlertConfigs()
+	is.True(len(configs) > 0)
+
+	help, ok := configs["help-request"]
+	is.True(ok)
+	is.True(help.Create.Event != nil)
+	is.True(help.Close.Event == nil)
+}
+
+func TestParseInvalidRegexp(t *testing.T) {
+	is := is.New(t)
+
+	_, err := Parse([]byte(`
+alerts:
+  device-comm:
+    create:
+      event:
+        keyMatches: '^responsive$'
+        valueMatches: '^(Ok$'
+`))
+	is.True(err != nil)
+	is.True(strings.Contains(err.Error(), "line 7, column 23"))
+}
+
+func TestParseStateQueries(t *testing.T) {
+	is := is.New(t)
+
+	cfg, err := Parse([]byte(`
+stateAlerts:
+  display-temperature:
+    query: 'device-type == "display" && temperature > 109'
+alerts:
+  device-offline:
+    create:
+      event:
+        keyMatches: '^online$'
+        valueDoesNotMatch: '^Online$'
+`))
+	is.NoErr(err)
+
+	query := cfg.StateAlertConfigs()["display-temperature"].Query
+	is.True(query != nil)
+
+	ok, err := query.Matches(map[string]interface{}{"device-type": "display", "temperature": float64(110)}, time.Now())
+	is.NoErr(err)
+	is.True(ok)
+
+	_, err = Parse([]byte(`
+stateAlerts:
+  display-temperature:
+    query: 'device-type == "display" && temperature >'
+`))
+	is.True(err != nil)
+	is.True(strings.Contains(err.Error(), "line 4, column 12"))
+
+	_, err = Parse([]byte(`
+stateAlerts:
+  display-temperature:
+    forScans: 2
+alerts:
+  device-offline:
+    create:
+      event:
+        keyMatches: '^online$'
+        valueDoesNotMatch: '^Online$'
+`))
+	is.True(err != nil)
+	is.True(strings.Contains(err.Error(), "stateAlerts.display-temperature.query"))
+}
+
+func TestParseStateThresholds(t *testing.T) {
+	is := is.New(t)
+
+	cfg, err := Parse([]byte(`
+stateAlerts:
+  sys-offline:
+    query: 'since(last-heartbeat) > $offlineAfter'
+    interval: 1m
+    thresholds:
+      offlineAfter: 6m
+    overrides:
+      - deviceType: scheduling-panel
+        thresholds:
+          offlineAfter: 15m
+      - deviceID: '-TC[0-9]+$'
+        thresholds:
+          offlineAfter: 1h
+alerts:
+  device-offline:
+    create:
+      event:
+        keyMatches: '^online$'
+        valueDoesNotMatch: '^Online$'
+`))
+	is.NoErr(err)
+
+	config := cfg.StateAlertConfigs()["sys-offline"]
+	is.Equal(config.Interval, time.Minute)
+
+	now := time.Now()
+	heartbeat := now.Add(-10 * time.Minute).Format(time.RFC3339Nano)
+
+	for _, tt := range []struct {
+		state map[string]interface{}
+		want  bool
+	}{
+		{map[string]interface{}{"deviceID": "ITB-1101-CP1", "last-heartbeat": heartbeat}, true},
+		{map[string]interface{}{"deviceID": "ITB-1101-SP1", "device-type": "scheduling-panel", "last-heartbeat": heartbeat}, false},
+		{map[string]interface{}{"deviceID": "ITB-1101-TC1", "last-heartbeat": heartbeat}, false},
+	} {
+		ok, err := config.Query.Matches(tt.state, now)
+		is.NoErr(err)
+		is.Equal(ok, tt.want)
+	}
+
+	_, err = Parse([]byte(`
+stateAlerts:
+  sys-offline:
+    query: 'since(last-heartbeat) > $offlineAfter'
+    thresholds:
+      offlineAfter: 6m
+    overrides:
+      - deviceType: scheduling-panel
+        thresholds:
+          offlineAfter: soon
+alerts:
+  device-offline:
+    create:
+      event:
+        keyMatches: '^online$'
+`))
+	is.True(err != nil)
+	is.True(strings.Contains(err.Error(), "stateAlerts.sys-offline.overrides[0].thresholds"))
+
+	_, err = Parse([]byte(`
+stateAlerts:
+  sys-offline:
+    query: 'since(last-heartbeat) > $offlineAfter'
+alerts:
+  device-offline:
+    create:
+      event:
+        keyMatches: '^online$'
+`))
+	is.True(err != nil)
+	is.True(strings.Contains(err.Error(), "stateAlerts.sys-offline.thresholds"))
+}
+
+func TestParseUnknownField(t *testing.T) {
+	is := is.New(t)
+
+	_, err := Parse([]byte(`
+alerts:
+  device-comm:
+    create:
+      event:
+        keyMatch: '^responsive$'
+`))
+	is.True(err != nil)
+}
+
+func TestParseMissingCreate(t *testing.T) {
+	is := is.New(t)
+
+	_, err := Parse([]byte(`
+alerts:
+  device-comm:
+    close:
+      event:
+        keyMatches: '^responsive$'
+`))
+	is.True(err != nil)
+	is.True(strings.Contains(err.Error(), "alerts.device-comm.create"))
+}
+
+func TestParseHysteresis(t *testing.T) {
+	is := is.New(t)
+
+	cfg, err := Parse([]byte(`
+alerts:
+  cpu-temperature:
+    create:
+      event:
+        keyMatches: '^thermal0-temp$'
+        value:
+          gt: 80
+    close:
+      hysteresis: 10
+`))
+	is.NoErr(err)
+
+	closing := cfg.AlertConfigs()["cpu-temperature"].Close.Event
+	is.True(closing != nil)
+	is.Equal(closing.KeyMatches.String(), "^thermal0-temp$")
+	is.Equal(closing.Value.Op, smee.NumericLessThan)
+	is.Equal(closing.Value.Threshold, 70.0)
+
+	cfg, err = Load("../../../../alerts.yaml")
+	is.NoErr(err)
+
+	memory := cfg.AlertConfigs()["memory-usage"]
+	is.Equal(*memory.Create.Event.Value, smee.NumericCondition{Op: smee.NumericAtLeast, Threshold: 90})
+	is.Equal(*memory.Close.Event.Value, smee.NumericCondition{Op: smee.NumericLessThan, Threshold: 90})
+}
+
+func TestParseInvalidNumericCondition(t *testing.T) {
+	is := is.New(t)
+
+	_, err := Parse([]byte(`
+alerts:
+  cpu-temperature:
+    create:
+      event:
+        keyMatches: '^thermal0-temp$'
+        value:
+          gt: 80
+          lt: 20
+`))
+	is.True(err != nil)
+	is.True(strings.Contains(err.Error(), "alerts.cpu-temperature.create.event.value"))
+}
+
+func TestParseInhibitSelf(t *testing.T) {
+	is := is.New(t)
+
+	_, err := Parse([]byte(`
+inhibitions:
+  - source: sys-offline
+    targets: [device-comm, sys-offline]
+alerts:
+  device-comm:
+    create:
+      event:
+        keyMatches: '^responsive$'
+`))
+	is.True(err != nil)
+	is.True(strings.Contains(err.Error(), "inhibitions[0].targets"))
+}
+
+func TestParseUnknownNotifier(t *testing.T) {
+	is := is.New(t)
+
+	_, err := Parse([]byte(`
+notifiers:
+  oncall:
+    chat:
+      url: https://chat.example.com/hook
+notifications:
+  - notifier: on-call
+alerts:
+  help-request:
+    create:
+      event:
+        keyMatches: '^help-request$'
+`))
+	is.True(err != nil)
+	is.True(strings.Contains(err.Error(), "notifications[0].notifier"))
+}
+
+func TestParseEscalation(t *testing.T) {
+	is := is.New(t)
+
+	cfg, err := Parse([]byte(`
+notifiers:
+  secondary:
+    chat:
+      url: https://chat.example.com/hook
+escalations:
+  help-request:
+    alertTypes: [help-request]
+    steps:
+      - after: 10m
+        notify: secondary
+      - after: 30m
+        createIncident: true
+alerts:
+  help-request:
+    create:
+      event:
+        keyMatches: '^help-request$'
+`))
+	is.NoErr(err)
+
+	policies := cfg.EscalationPolicies()
+	is.Equal(len(policies), 1)
+	is.Equal(len(policies[0].Steps), 2)
+	is.Equal(policies[0].Steps[0].Notify, "secondary")
+	is.True(policies[0].Steps[1].CreateIncident)
+
+	_, err = Parse([]byte(`
+escalations:
+  help-request:
+    steps:
+      - after: 30m
+        createIncident: true
+      - after: 10m
+        createIncident: true
+alerts:
+  help-request:
+    create:
+      event:
+        keyMatches: '^help-request$'
+`))
+	is.True(err != nil)
+	is.True(strings.Contains(err.Error(), "escalations.help-request.steps[1].after"))
+}
+
+func TestParseIncidents(t *testing.T) {
+	is := is.New(t)
+
+	cfg, err := Parse([]byte(`
+incidents:
+  help-request:
+    shortDescription: '{{.Room}} help request from {{.Device}}{{with .KBArticle}} - {{.}}{{end}}'
+  sys-offline: {}
+alerts:
+  help-request:
+    create:
+      event:
+        keyMatches: '^help-request$'
+`))
+	is.NoErr(err)
+
+	rules := cfg.IncidentRules()
+	is.Equal(len(rules), 2)
+
+	var buf bytes.Buffer
+	is.NoErr(rules["help-request"].ShortDescription.Execute(&buf, smee.IncidentTemplateData{Room: "ITB-1101", Device: "ITB-1101-CP1", Type: "help-request", KBArticle: "KB0012345"}))
+	is.Equal(buf.String(), "ITB-1101 help request from ITB-1101-CP1 - KB0012345")
+
+	buf.Reset()
+	is.NoErr(rules["sys-offline"].ShortDescription.Execute(&buf, smee.IncidentTemplateData{Room: "ITB-1101", Device: "ITB-1101-CP1", Type: "sys-offline"}))
+	is.Equal(buf.String(), "ITB-1101 ITB-1101-CP1: sys-offline")
+
+	_, err = Parse([]byte(`
+incidents:
+  help-request:
+    shortDescription: '{{.Building}}'
+alerts:
+  help-request:
+    create:
+      event:
+        keyMatches: '^help-request$'
+`))
+	is.True(err != nil)
+	is.True(strings.Contains(err.Error(), "incidents.help-request.shortDescription"))
+}
+
+func TestParseCorrelations(t *testing.T) {
+	is := is.New(t)
+
+	cfg, err := Parse([]byte(`
+correlations:
+  room-outage:
+    alertTypes: [device-offline, touchpanel-offline]
+    minDevices: 3
+    within: 90s
+alerts:
+  device-offline:
+    create:
+      event:
+        keyMatches: '^online$'
+        valueDoesNotMatch: '^Online$'
+`))
+	is.NoErr(err)
+
+	rules := cfg.CorrelationRules()
+	is.Equal(len(rules), 1)
+	is.Equal(rules[0].Type, "room-outage")
+	is.Equal(rules[0].MinDevices, 3)
+	is.Equal(rules[0].Within, 90*time.Second)
+	is.True(rules[0].Correlates("touchpanel-offline"))
+	is.True(!rules[0].Correlates("room-outage"))
+
+	_, err = Parse([]byte(`
+correlations:
+  device-offline:
+    alertTypes: [touchpanel-offline]
+    minDevices: 3
+    within: 90s
+alerts:
+  device-offline:
+    create:
+      event:
+        keyMatches: '^online$'
+`))
+	is.True(err != nil)
+	is.True(strings.Contains(err.Error(), "correlations.device-offline"))
+
+	_, err = Parse([]byte(`
+correlations:
+  room-outage:
+    alertTypes: [device-offline]
+    minDevices: 1
+    within: 90s
+alerts:
+  device-offline:
+    create:
+      event:
+        keyMatches: '^online$'
+`))
+	is.True(err != nil)
+	is.True(strings.Contains(err.Error(), "correlations.room-outage.minDevices"))
+}
+
+func TestParseBuildingOutage(t *testing.T) {
+	is := is.New(t)
+
+	cfg, err := Parse([]byte(`
+buildingOutage:
+  minRooms: 5
+  within: 5m
+  groups:
+    HBLL: [HBLL-, LIB-]
+alerts:
+  device-offline:
+    create:
+      event:
+        keyMatches: '^online$'
+`))
+	is.NoErr(err)
+
+	rule := cfg.BuildingRule()
+	is.Equal(rule.Type, DefaultBuildingOutageType)
+	is.Equal(rule.MinRooms, 5)
+	is.Equal(rule.Within, 5*time.Minute)
+	is.Equal(rule.Building("LIB-1102"), "HBLL")
+
+	_, err = Parse([]byte(`
+buildingOutage:
+  minRooms: 5
+  within: 5m
+  groups:
+    HBLL: []
+alerts:
+  device-offline:
+    create:
+      event:
+        keyMatches: '^online$'
+`))
+	is.True(err != nil)
+	is.True(strings.Contains(err.Error(), "buildingOutage.groups.HBLL"))
+}
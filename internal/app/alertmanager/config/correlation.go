@@ -0,0 +1,81 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/byuoitav/smee/internal/smee"
+)
+
+// Correlation opens one alert for a room when MinDevices distinct devices in
+// it have active alerts of AlertTypes that started within Within of each other
+type Correlation struct {
+	AlertTypes []string `yaml:"alertTypes"`
+	MinDevices int      `yaml:"minDevices"`
+	Within     Duration `yaml:"within"`
+}
+
+func (c Config) validateCorrelations() error {
+	var types []string
+	for typ := range c.Correlations {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+
+	for _, typ := range types {
+		// correlated alerts are only opened and closed by their rule
+		if _, ok := c.Alerts[typ]; ok {
+			return fmt.Errorf("correlations.%s: %s is already an alert type", typ, typ)
+		}
+
+		if _, ok := c.StateAlerts[typ]; ok {
+			return fmt.Errorf("correlations.%s: %s is already a state alert type", typ, typ)
+		}
+
+		if err := c.Correlations[typ].validate(typ); err != nil {
+			return fmt.Errorf("correlations.%s.%w", typ, err)
+		}
+	}
+
+	return nil
+}
+
+func (corr Correlation) validate(typ string) error {
+	switch {
+	case len(corr.AlertTypes) == 0:
+		return errors.New("alertTypes: at least one alert type is required")
+	case corr.MinDevices < 2:
+		return errors.New("minDevices: must be at least 2")
+	case corr.Within <= 0:
+		return errors.New("within: is required")
+	}
+
+	for _, t := range corr.AlertTypes {
+		if t == typ {
+			return fmt.Errorf("alertTypes: %s can't correlate itself", t)
+		}
+	}
+
+	return nil
+}
+
+// CorrelationRules converts the configured correlations into smee.CorrelationRules.
+func (c Config) CorrelationRules() []smee.CorrelationRule {
+	var rules []smee.CorrelationRule
+	for typ, corr := range c.Correlations {
+		rules = append(rules, smee.CorrelationRule{
+			Type:       typ,
+			AlertTypes: corr.AlertTypes,
+			MinDevices: corr.MinDevices,
+			Within:     time.Duration(corr.Within),
+		})
+	}
+
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].Type < rules[j].Type
+	})
+
+	return rules
+}
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/byuoitav/smee/internal/smee"
+)
+
+// Escalation escalates issues that haven't been acknowledged. An empty
+// filter matches every issue.
+type Escalation struct {
+	AlertTypes   []string         `yaml:"alertTypes"`
+	RoomPrefixes []string         `yaml:"roomPrefixes"`
+	Steps        []EscalationStep `yaml:"steps"`
+}
+
+type EscalationStep struct {
+	// After is how long the issue has to go unacknowledged before this step runs
+	After Duration `yaml:"after"`
+
+	// Notify is the name of a notifier (from notifiers) to send the escalation to
+	Notify string `yaml:"notify"`
+
+	// CreateIncident creates a ServiceNow incident and links it to the issue,
+	// unless the issue already has an incident
+	CreateIncident bool `yaml:"createIncident"`
+}
+
+func (c Config) validateEscalations() error {
+	var names []string
+	for name := range c.Escalations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		esc := c.Escalations[name]
+		if len(esc.Steps) == 0 {
+			return fmt.Errorf("escalations.%s.steps: at least one step is required", name)
+		}
+
+		var prev time.Duration
+		for i, step := range esc.Steps {
+			switch {
+			case step.After <= 0:
+				return fmt.Errorf("escalations.%s.steps[%d].after: must be positive", name, i)
+			case time.Duration(step.After) < prev:
+				return fmt.Errorf("escalations.%s.steps[%d].after: must not be before the previous step", name, i)
+			case step.Notify == "" && !step.CreateIncident:
+				return fmt.Errorf("escalations.%s.steps[%d]: must notify or createIncident", name, i)
+			}
+
+			if step.Notify != "" {
+				if _, ok := c.Notifiers[step.Notify]; !ok {
+					return fmt.Errorf("escalations.%s.steps[%d].notify: unknown notifier %q", name, i, step.Notify)
+				}
+			}
+
+			prev = time.Duration(step.After)
+		}
+	}
+
+	return nil
+}
+
+// EscalationPolicies converts the configured escalations into smee.EscalationPolicies, sorted by name.
+func (c Config) EscalationPolicies() []smee.EscalationPolicy {
+	var names []string
+	for name := range c.Escalations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var policies []smee.EscalationPolicy
+	for _, name := range names {
+		esc := c.Escalations[name]
+		policy := smee.EscalationPolicy{
+			Name:         name,
+			AlertTypes:   esc.AlertTypes,
+			RoomPrefixes: esc.RoomPrefixes,
+		}
+
+		for _, step := range esc.Steps {
+			policy.Steps = append(policy.Steps, smee.EscalationStep{
+				After:          time.Duration(step.After),
+				Notify:         step.Notify,
+				CreateIncident: step.CreateIncident,
+			})
+		}
+
+		policies = append(policies, policy)
+	}
+
+	return policies
+}
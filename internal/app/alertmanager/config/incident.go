@@ -0,0 +1,81 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"text/template"
+
+	"github.com/byuoitav/smee/internal/smee"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultIncidentShortDescription is used by incidents without a shortDescription
+const DefaultIncidentShortDescription = "{{.Room}} {{.Device}}: {{.Type}}{{with .KBArticle}} ({{.}}){{end}}"
+
+// Incident opens a ServiceNow incident when an alert of its type is created
+type Incident struct {
+	// ShortDescription is a text/template executed with the room, device,
+	// alert type, and KB article of the alert
+	ShortDescription *Template `yaml:"shortDescription"`
+}
+
+// Template is a text/template that is parsed when it is unmarshaled,
+// so that invalid templates are reported with their location in the file.
+type Template struct {
+	*template.Template
+}
+
+func (t *Template) UnmarshalYAML(node *yaml.Node) error {
+	var text string
+	if err := node.Decode(&text); err != nil {
+		return err
+	}
+
+	tmpl, err := template.New("").Parse(text)
+	if err != nil {
+		return fmt.Errorf("line %d, column %d: invalid template %q: %w", node.Line, node.Column, text, err)
+	}
+
+	t.Template = tmpl
+	return nil
+}
+
+func (c Config) validateIncidents() error {
+	var types []string
+	for typ := range c.Incidents {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+
+	for _, typ := range types {
+		inc := c.Incidents[typ]
+		if inc.ShortDescription == nil {
+			continue
+		}
+
+		// catches references to fields that don't exist
+		if err := inc.ShortDescription.Execute(ioutil.Discard, smee.IncidentTemplateData{}); err != nil {
+			return fmt.Errorf("incidents.%s.shortDescription: %w", typ, err)
+		}
+	}
+
+	return nil
+}
+
+// IncidentRules converts the configured incidents into a map of alert type -> smee.IncidentRule.
+func (c Config) IncidentRules() map[string]smee.IncidentRule {
+	rules := make(map[string]smee.IncidentRule, len(c.Incidents))
+	for typ, inc := range c.Incidents {
+		tmpl := template.Must(template.New("").Parse(DefaultIncidentShortDescription))
+		if inc.ShortDescription != nil {
+			tmpl = inc.ShortDescription.Template
+		}
+
+		rules[typ] = smee.IncidentRule{
+			ShortDescription: tmpl,
+		}
+	}
+
+	return rules
+}
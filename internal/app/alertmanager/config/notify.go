@@ -0,0 +1,123 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/byuoitav/smee/internal/smee"
+)
+
+// Notifier is somewhere notifications can be sent. Exactly one of its
+// fields must be set. Strings like URLs and passwords have environment
+// variables (${VAR}) expanded when the notifier is built, so secrets don't
+// have to live in this file.
+type Notifier struct {
+	Webhook *WebhookNotifier `yaml:"webhook"`
+	Email   *EmailNotifier   `yaml:"email"`
+	Chat    *ChatNotifier    `yaml:"chat"`
+}
+
+// WebhookNotifier POSTs each notification as JSON to URL
+type WebhookNotifier struct {
+	URL     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers"`
+}
+
+// EmailNotifier sends each notification as an email over SMTP
+type EmailNotifier struct {
+	Host     string   `yaml:"host"`
+	Port     int      `yaml:"port"`
+	Username string   `yaml:"username"`
+	Password string   `yaml:"password"`
+	From     string   `yaml:"from"`
+	To       []string `yaml:"to"`
+}
+
+// ChatNotifier posts a one line message to a chat incoming webhook (Slack,
+// Google Chat, Teams, etc.) that accepts {"text": "..."}
+type ChatNotifier struct {
+	URL string `yaml:"url"`
+}
+
+// NotificationRoute sends notifications that match all of its filters to
+// Notifier. An empty filter matches everything.
+type NotificationRoute struct {
+	Notifier string `yaml:"notifier"`
+
+	RoomPrefixes []string                `yaml:"roomPrefixes"`
+	AlertTypes   []string                `yaml:"alertTypes"`
+	Events       []smee.NotificationType `yaml:"events"`
+}
+
+func (n Notifier) validate() error {
+	count := 0
+	if n.Webhook != nil {
+		count++
+		if n.Webhook.URL == "" {
+			return fmt.Errorf("webhook.url: is required")
+		}
+	}
+
+	if n.Email != nil {
+		count++
+		switch {
+		case n.Email.Host == "":
+			return fmt.Errorf("email.host: is required")
+		case n.Email.From == "":
+			return fmt.Errorf("email.from: is required")
+		case len(n.Email.To) == 0:
+			return fmt.Errorf("email.to: at least one recipient is required")
+		}
+	}
+
+	if n.Chat != nil {
+		count++
+		if n.Chat.URL == "" {
+			return fmt.Errorf("chat.url: is required")
+		}
+	}
+
+	if count != 1 {
+		return fmt.Errorf("exactly one of webhook, email, or chat must be set")
+	}
+
+	return nil
+}
+
+func (c Config) validateNotifications() error {
+	var names []string
+	for name := range c.Notifiers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := c.Notifiers[name].validate(); err != nil {
+			return fmt.Errorf("notifiers.%s.%w", name, err)
+		}
+	}
+
+	for i, route := range c.Notifications {
+		if _, ok := c.Notifiers[route.Notifier]; !ok {
+			return fmt.Errorf("notifications[%d].notifier: unknown notifier %q", i, route.Notifier)
+		}
+
+		for _, event := range route.Events {
+			if !validNotificationType(event) {
+				return fmt.Errorf("notifications[%d].events: unknown event %q", i, event)
+			}
+		}
+	}
+
+	return nil
+}
+
+func validNotificationType(typ smee.NotificationType) bool {
+	for _, t := range smee.NotificationTypes {
+		if t == typ {
+			return true
+		}
+	}
+
+	return false
+}
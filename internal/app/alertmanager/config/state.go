@@ -0,0 +1,147 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/byuoitav/smee/internal/pkg/expr"
+	"github.com/byuoitav/smee/internal/smee"
+)
+
+// StateOverride changes a state alert's thresholds for the devices that
+// match it. At least one of DeviceType and DeviceID must be set; if both
+// are, a device has to match both.
+type StateOverride struct {
+	// DeviceType matches the device-type field of the device's state
+	DeviceType string `yaml:"deviceType"`
+
+	// DeviceID matches the device's ID
+	DeviceID *Regexp `yaml:"deviceID"`
+
+	// Thresholds replace the state alert's thresholds with the same name
+	Thresholds map[string]string `yaml:"thresholds"`
+}
+
+func (s StateAlert) validate() error {
+	switch {
+	case s.Query == nil:
+		return errors.New("query: is required")
+	case s.Interval < 0:
+		return errors.New("interval: must not be negative")
+	case s.ForScans < 0:
+		return errors.New("forScans: must not be negative")
+	}
+
+	if err := s.Flapping.validate(); err != nil {
+		return fmt.Errorf("flapping: %w", err)
+	}
+
+	if _, err := s.Query.Bind(thresholds(s.Thresholds, nil)); err != nil {
+		return fmt.Errorf("thresholds: %w", err)
+	}
+
+	for i, o := range s.Overrides {
+		if o.DeviceType == "" && o.DeviceID == nil {
+			return fmt.Errorf("overrides[%d]: deviceType or deviceID is required", i)
+		}
+
+		if _, err := s.Query.Bind(thresholds(s.Thresholds, o.Thresholds)); err != nil {
+			return fmt.Errorf("overrides[%d].thresholds: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// query returns s's query with its thresholds bound
+func (s StateAlert) query() smee.DeviceStateQuery {
+	if s.Query == nil || s.Query.Expr == nil {
+		return nil
+	}
+
+	def, err := s.Query.Bind(thresholds(s.Thresholds, nil))
+	if err != nil {
+		return nil
+	}
+
+	q := &stateQuery{query: def}
+	for _, o := range s.Overrides {
+		bound, err := s.Query.Bind(thresholds(s.Thresholds, o.Thresholds))
+		if err != nil {
+			return nil
+		}
+
+		q.overrides = append(q.overrides, stateQueryOverride{
+			deviceType: o.DeviceType,
+			deviceID:   o.DeviceID.regexp(),
+			query:      bound,
+		})
+	}
+
+	return q
+}
+
+// thresholds merges overrides into defaults, returning them as parameter values
+func thresholds(defaults, overrides map[string]string) map[string]interface{} {
+	params := make(map[string]interface{}, len(defaults))
+	for name, val := range defaults {
+		params[name] = expr.ParseValue(val)
+	}
+
+	for name, val := range overrides {
+		params[name] = expr.ParseValue(val)
+	}
+
+	return params
+}
+
+// stateQuery runs query against devices, or the query of the first override
+// that matches the device
+type stateQuery struct {
+	query     *expr.Expr
+	overrides []stateQueryOverride
+}
+
+type stateQueryOverride struct {
+	deviceType string
+	deviceID   *regexp.Regexp
+	query      *expr.Expr
+}
+
+func (q *stateQuery) Matches(state map[string]interface{}, now time.Time) (bool, error) {
+	return q.forDevice(state).Matches(state, now)
+}
+
+func (q *stateQuery) NextChange(state map[string]interface{}, now time.Time) (time.Time, bool) {
+	return q.forDevice(state).NextChange(state, now)
+}
+
+func (q *stateQuery) String() string {
+	return q.query.String()
+}
+
+// forDevice returns the query to run against the device with state
+func (q *stateQuery) forDevice(state map[string]interface{}) *expr.Expr {
+	if len(q.overrides) == 0 {
+		return q.query
+	}
+
+	deviceType, _ := state["device-type"].(string)
+	deviceID, _ := state["deviceID"].(string)
+
+	for _, o := range q.overrides {
+		if o.deviceType != "" && o.deviceType != deviceType {
+			continue
+		}
+
+		if o.deviceID != nil && !o.deviceID.MatchString(deviceID) {
+			continue
+		}
+
+		return o.query
+	}
+
+	return q.query
+}
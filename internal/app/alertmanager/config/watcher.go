@@ -0,0 +1,84 @@
+package config
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Watcher reloads a config file when the process receives a SIGHUP or
+// when the file's modification time changes.
+type Watcher struct {
+	Path string
+	Log  *zap.Logger
+
+	// Interval is how often the file is checked for changes. Defaults to 15 seconds.
+	Interval time.Duration
+
+	// OnReload is called with every new config, after the reload func passed to Watch
+	OnReload []func(Config)
+}
+
+// Watch calls reload with the new config every time the file changes. If the
+// file becomes invalid, the error is logged and reload is not called, so the
+// last good config stays in place.
+func (w *Watcher) Watch(ctx context.Context, reload func(Config)) error {
+	interval := w.Interval
+	if interval == 0 {
+		interval = 15 * time.Second
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	modTime := w.modTime()
+
+	load := func(reason string) {
+		cfg, err := Load(w.Path)
+		if err != nil {
+			w.Log.Error("unable to reload config, keeping previous config", zap.String("reason", reason), zap.Error(err))
+			return
+		}
+
+		w.Log.Info("Reloaded config", zap.String("reason", reason), zap.String("path", w.Path), zap.Int("alertTypes", len(cfg.Alerts)))
+		reload(cfg)
+		for _, f := range w.OnReload {
+			f(cfg)
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-hup:
+			modTime = w.modTime()
+			load("SIGHUP")
+		case <-ticker.C:
+			cur := w.modTime()
+			if cur.Equal(modTime) {
+				continue
+			}
+
+			modTime = cur
+			load("file changed")
+		}
+	}
+}
+
+func (w *Watcher) modTime() time.Time {
+	info, err := os.Stat(w.Path)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return info.ModTime()
+}
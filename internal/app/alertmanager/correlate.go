@@ -0,0 +1,153 @@
+package alertmanager
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/byuoitav/smee/internal/smee"
+	"go.uber.org/zap"
+)
+
+func (m *Manager) correlationRules() []smee.CorrelationRule {
+	m.configMu.RLock()
+	defer m.configMu.RUnlock()
+	return m.CorrelationRules
+}
+
+// correlate runs the correlation rules for a newly created alert. If its
+// room already has an active correlated alert, alert is marked as one of its
+// children. Otherwise the correlated alert is created once enough devices in
+// the room are failing together.
+func (m *Manager) correlate(ctx context.Context, issue smee.Issue, alert smee.Alert) {
+	for _, rule := range m.correlationRules() {
+		if !rule.Correlates(alert.Type) {
+			continue
+		}
+
+		issue = m.correlateRule(ctx, rule, issue, alert)
+	}
+}
+
+// correlateRule returns issue after applying rule to it
+func (m *Manager) correlateRule(ctx context.Context, rule smee.CorrelationRule, issue smee.Issue, alert smee.Alert) smee.Issue {
+	var parent smee.Alert
+	var children []string
+	devices := make(map[string]bool)
+
+	for _, a := range issue.Alerts {
+		switch {
+		case !a.Active():
+		case a.Type == rule.Type:
+			parent = a
+		case rule.Correlates(a.Type) && !hasActiveParent(issue, a) && absDuration(alert.Start.Sub(a.Start)) <= rule.Within:
+			children = append(children, a.ID)
+			devices[a.Device.ID] = true
+		}
+	}
+
+	if len(children) == 0 {
+		return issue
+	}
+
+	if parent.ID == "" {
+		if len(devices) < rule.MinDevices {
+			return issue
+		}
+
+		var ok bool
+		if parent, issue, ok = m.createCorrelatedAlert(ctx, rule, alert.Device.Room, len(devices)); !ok {
+			return issue
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	updated, err := m.IssueStore.SetAlertParent(ctx, issue.ID, parent.ID, children...)
+	if err != nil {
+		m.Log.Error("unable to set alert parent", zap.Error(err), zap.String("issueID", issue.ID), zap.String("parentID", parent.ID), zap.Strings("alertIDs", children))
+		return issue
+	}
+
+	m.Log.Info("Correlated alerts", zap.String("roomID", issue.Room.ID), zap.String("type", rule.Type), zap.String("parentID", parent.ID), zap.Strings("alertIDs", children))
+	return updated
+}
+
+// createCorrelatedAlert creates the alert for rule in room, returning it and
+// its issue. Correlated alerts are created on a device with the room's ID.
+func (m *Manager) createCorrelatedAlert(ctx context.Context, rule smee.CorrelationRule, room smee.Room, devices int) (smee.Alert, smee.Issue, bool) {
+	alert := smee.Alert{
+		Device: smee.Device{
+			ID:   room.ID,
+			Room: room,
+		},
+		Type:  rule.Type,
+		Start: time.Now(),
+	}
+
+	if s, ok := m.silencedBy(ctx, alert); ok {
+		m.Log.Debug("Not creating silenced correlated alert", zap.String("roomID", room.ID), zap.String("type", rule.Type), zap.String("silenceID", s.ID))
+		return smee.Alert{}, smee.Issue{}, false
+	}
+
+	events := []smee.IssueEvent{
+		{
+			Type:      smee.TypeSystemMessage,
+			Timestamp: alert.Start,
+			Data:      smee.NewSystemMessage(fmt.Sprintf("AV Bot: %v devices failed within %v of each other, probable %v", devices, rule.Within, rule.Type)),
+		},
+	}
+
+	issue, ok := m.createAlert(ctx, alert, events)
+	if !ok {
+		return smee.Alert{}, smee.Issue{}, false
+	}
+
+	for _, a := range issue.Alerts {
+		if a.Active() && a.Type == rule.Type {
+			m.queueAlertIncident(issue, a)
+			return a, issue, true
+		}
+	}
+
+	return smee.Alert{}, smee.Issue{}, false
+}
+
+// closeCorrelatedAlert closes the parent of alert, which was just closed on
+// issue, once none of the parent's children are active
+func (m *Manager) closeCorrelatedAlert(ctx context.Context, issue smee.Issue, alert smee.Alert) {
+	closed, ok := issue.Alerts[alert.ID]
+	if !ok || !hasActiveParent(issue, closed) {
+		return
+	}
+
+	parent := issue.Alerts[closed.ParentID]
+	for _, a := range issue.Alerts {
+		if a.Active() && a.ParentID == parent.ID {
+			return
+		}
+	}
+
+	m.closeAlert(ctx, parent, []smee.IssueEvent{
+		{
+			Type:      smee.TypeSystemMessage,
+			Timestamp: time.Now(),
+			Data:      smee.NewSystemMessage(fmt.Sprintf("AV Bot: every device in the %v has recovered", parent.Type)),
+		},
+	})
+}
+
+// hasActiveParent returns true if alert is the child of an active alert on issue
+func hasActiveParent(issue smee.Issue, alert smee.Alert) bool {
+	parent, ok := issue.Alerts[alert.ParentID]
+	return ok && parent.Active()
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+
+	return d
+}
@@ -0,0 +1,62 @@
+package alertmanager
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/byuoitav/smee/internal/smee"
+	"github.com/matryer/is"
+)
+
+// closeActive runs the close action of every active alert of typ on deviceID
+func closeActive(ctx context.Context, m *Manager, roomID, deviceID, typ string) {
+	for _, alert := range m.active.device(roomID, deviceID) {
+		if alert.Type == typ {
+			m.runAlertAction(ctx, alertAction{action: "close", alert: alert})
+		}
+	}
+}
+
+func TestCorrelation(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	m, issues := newTestManager(nil)
+	m.CorrelationRules = []smee.CorrelationRule{
+		{
+			Type:       "room-outage",
+			AlertTypes: []string{"device-offline"},
+			MinDevices: 3,
+			Within:     2 * time.Minute,
+		},
+	}
+
+	devices := []string{"ITB-1101-CP1", "ITB-1101-D1", "ITB-1101-D2"}
+	for _, dev := range devices {
+		m.runAlertAction(ctx, createAction("ITB-1101", dev, "device-offline"))
+	}
+
+	is.Equal(issues.created["room-outage"], 1)
+
+	issue, err := issues.ActiveIssue(ctx, "ITB-1101")
+	is.NoErr(err)
+
+	outage := m.active.device("ITB-1101", "ITB-1101")
+	is.Equal(len(outage), 1)
+
+	for _, a := range issue.Alerts {
+		if a.Type == "device-offline" {
+			is.Equal(a.ParentID, outage[0].ID)
+		}
+	}
+
+	// the outage stays open until every device has recovered
+	for i, dev := range devices {
+		closeActive(ctx, m, "ITB-1101", dev, "device-offline")
+		is.Equal(m.active.contains(outage[0]), i < len(devices)-1)
+	}
+
+	_, err = issues.ActiveIssue(ctx, "ITB-1101")
+	is.Equal(err, smee.ErrRoomIssueNotFound)
+}
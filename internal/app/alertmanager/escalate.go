@@ -0,0 +1,211 @@
+package alertmanager
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/byuoitav/smee/internal/smee"
+	"go.uber.org/zap"
+)
+
+func (m *Manager) escalationPolicies() []smee.EscalationPolicy {
+	m.configMu.RLock()
+	defer m.configMu.RUnlock()
+	return m.Escalations
+}
+
+// manageEscalations runs the escalation steps for issues that have gone
+// unacknowledged for too long
+func (m *Manager) manageEscalations(ctx context.Context) error {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case now := <-ticker.C:
+			policies := m.escalationPolicies()
+			if len(policies) == 0 {
+				continue
+			}
+
+			issues, err := m.IssueStore.ActiveIssues(ctx)
+			if err != nil {
+				m.Log.Error("unable to get active issues to escalate", zap.Error(err))
+				continue
+			}
+
+			for _, issue := range issues {
+				if !issue.Acknowledged_Time.IsZero() {
+					continue
+				}
+
+				for _, policy := range policies {
+					m.escalate(ctx, issue, policy, now)
+				}
+			}
+		}
+	}
+}
+
+// escalate runs each of policy's steps that are due for issue and haven't run yet
+func (m *Manager) escalate(ctx context.Context, issue smee.Issue, policy smee.EscalationPolicy, now time.Time) {
+	since, types, ok := unacknowledgedSince(issue, policy)
+	if !ok {
+		return
+	}
+
+	done := escalatedSteps(issue, policy.Name, since)
+
+	for i, step := range policy.Steps {
+		if done[i] || now.Sub(since) < step.After {
+			continue
+		}
+
+		msg := fmt.Sprintf("AV Bot: %v not acknowledged after %v, escalating (%v step %v)", strings.Join(types, ", "), step.After, policy.Name, i+1)
+		var results []string
+
+		if step.Notify != "" {
+			if err := m.notifyEscalation(ctx, issue, step.Notify, msg); err != nil {
+				m.Log.Warn("unable to send escalation", zap.Error(err), zap.String("issueID", issue.ID), zap.String("policy", policy.Name), zap.String("notifier", step.Notify))
+				results = append(results, fmt.Sprintf("unable to notify %v", step.Notify))
+			} else {
+				results = append(results, fmt.Sprintf("notified %v", step.Notify))
+			}
+		}
+
+		if step.CreateIncident {
+			inc, err := m.createEscalationIncident(ctx, issue, types)
+			switch {
+			case err != nil:
+				m.Log.Warn("unable to create escalation incident", zap.Error(err), zap.String("issueID", issue.ID), zap.String("policy", policy.Name))
+				results = append(results, "unable to create incident")
+			case inc.ID == "":
+				results = append(results, "incident already linked or being opened")
+			default:
+				results = append(results, fmt.Sprintf("created incident %v", inc.Name))
+			}
+		}
+
+		m.Log.Info("Escalated issue", zap.String("issueID", issue.ID), zap.String("roomID", issue.Room.ID), zap.String("policy", policy.Name), zap.Int("step", i+1))
+
+		// the step is recorded even if it failed, so that a broken notifier
+		// doesn't get retried every minute
+		event := smee.IssueEvent{
+			Type:      smee.TypeEscalation,
+			Timestamp: now,
+			Data:      smee.NewEscalationMessage(policy.Name, i, fmt.Sprintf("%v: %v", msg, strings.Join(results, ", "))),
+		}
+
+		if err := m.IssueStore.AddIssueEvents(ctx, issue.ID, event); err != nil {
+			m.Log.Error("unable to add escalation event", zap.Error(err), zap.String("issueID", issue.ID))
+		}
+	}
+}
+
+// unacknowledgedSince returns the start of the earliest active, unacknowledged
+// alert on issue that policy applies to, and the types of those alerts.
+func unacknowledgedSince(issue smee.Issue, policy smee.EscalationPolicy) (time.Time, []string, bool) {
+	if len(policy.RoomPrefixes) > 0 {
+		matched := false
+		for _, prefix := range policy.RoomPrefixes {
+			if strings.HasPrefix(issue.Room.ID, prefix) {
+				matched = true
+				break
+			}
+		}
+
+		if !matched {
+			return time.Time{}, nil, false
+		}
+	}
+
+	var since time.Time
+	seen := make(map[string]bool)
+	var types []string
+
+	for _, alert := range issue.Alerts {
+		if !alert.Active() || !alert.Acknowledged_Time.IsZero() {
+			continue
+		}
+
+		if len(policy.AlertTypes) > 0 && !containsString(policy.AlertTypes, alert.Type) {
+			continue
+		}
+
+		if since.IsZero() || alert.Start.Before(since) {
+			since = alert.Start
+		}
+
+		if !seen[alert.Type] {
+			seen[alert.Type] = true
+			types = append(types, alert.Type)
+		}
+	}
+
+	sort.Strings(types)
+	return since, types, !since.IsZero()
+}
+
+// escalatedSteps returns the steps of policy that have already run on issue since since
+func escalatedSteps(issue smee.Issue, policy string, since time.Time) map[int]bool {
+	done := make(map[int]bool)
+	for _, event := range issue.Events {
+		if event.Type != smee.TypeEscalation || event.Timestamp.Before(since) {
+			continue
+		}
+
+		data, err := event.ParseData()
+		if err != nil {
+			continue
+		}
+
+		if msg, ok := data.(smee.EscalationMessage); ok && msg.Policy == policy {
+			done[msg.Step] = true
+		}
+	}
+
+	return done
+}
+
+func (m *Manager) notifyEscalation(ctx context.Context, issue smee.Issue, notifier, msg string) error {
+	if m.Notifier == nil {
+		return fmt.Errorf("no notifier configured")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	return m.Notifier.NotifyNamed(ctx, notifier, smee.Notification{
+		Type:      smee.NotifyIssueEscalated,
+		Timestamp: time.Now(),
+		Issue:     issue,
+		Message:   msg,
+	})
+}
+
+// createEscalationIncident creates an incident for issue and links it. If the
+// issue already has an incident, or an incident rule is opening one, no
+// incident is created and the returned incident is empty.
+func (m *Manager) createEscalationIncident(ctx context.Context, issue smee.Issue, types []string) (smee.Incident, error) {
+	if len(issue.Incidents) > 0 || !m.claimIncident(issue.ID) {
+		return smee.Incident{}, nil
+	}
+	defer m.releaseIncident(issue.ID)
+
+	return m.createIncident(ctx, issue, fmt.Sprintf("%v: %v (unacknowledged)", issue.Room.ID, strings.Join(types, ", ")))
+}
+
+func containsString(list []string, s string) bool {
+	for i := range list {
+		if list[i] == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -5,131 +5,92 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/byuoitav/smee/internal/app/alertmanager/rules"
 	"github.com/byuoitav/smee/internal/smee"
 	"go.uber.org/zap"
 )
 
 func (m *Manager) generateEventAlerts(ctx context.Context) error {
-	// stream setup timeout?
-	stream, err := m.EventStreamer.Stream(ctx)
-	if err != nil {
-		return fmt.Errorf("unable to start event stream: %w", err)
-	}
+	return m.streamEvents(ctx, "generate", m.generateEventAlert)
+}
 
-	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			event, err := stream.Next(ctx)
-			if err != nil {
-				return fmt.Errorf("unable to get next event: %w", err)
-			}
+func (m *Manager) generateEventAlert(ctx context.Context, event smee.Event) {
+	configs := m.alertConfigs()
+	for _, typ := range rules.Creates(configs, event, m.transitionError) {
+		config := configs[typ]
+		alert := smee.Alert{
+			Device: smee.Device{
+				ID: event.DeviceID,
+				Room: smee.Room{
+					ID: event.RoomID,
+				},
+			},
+			Type:  typ,
+			Start: time.Now(),
+		}
 
-			for typ, config := range m.AlertConfigs {
-				trans := config.Create.Event
-				switch {
-				case trans == nil:
-					continue
-				case trans.KeyMatches != nil && !trans.KeyMatches.MatchString(event.Key):
-					continue
-				case trans.KeyDoesNotMatch != nil && trans.KeyDoesNotMatch.MatchString(event.Key):
-					continue
-				case trans.ValueMatches != nil && !trans.ValueMatches.MatchString(event.Value):
-					continue
-				case trans.ValueDoesNotMatch != nil && trans.ValueDoesNotMatch.MatchString(event.Value):
-					continue
-				}
+		alert.Seen(event.Value, alert.Start)
 
-				alert := smee.Alert{
-					Device: smee.Device{
-						ID: event.DeviceID,
-						Room: smee.Room{
-							ID: event.RoomID,
-						},
-					},
-					Type:  typ,
-					Start: time.Now(),
-				}
+		action := alertAction{
+			action: "create",
+			alert:  alert,
+			events: []smee.IssueEvent{
+				{
+					Type:      smee.TypeSystemMessage,
+					Timestamp: time.Now(),
+					Data:      smee.NewSystemMessage(fmt.Sprintf("AV Bot: |%v| %v alert started (Value: %v)", event.DeviceID, typ, event.Value)),
+				},
+			},
+		}
 
-				m.queue <- alertAction{
-					action: "create",
-					alert:  alert,
-					events: []smee.IssueEvent{
-						{
-							Type:      smee.TypeSystemMessage,
-							Timestamp: time.Now(),
-							Data:      smee.NewSystemMessage(fmt.Sprintf("AV Bot: |%v| %v alert started (Value: %v)", event.DeviceID, typ, event.Value)),
-						},
-					},
-				}
-			}
+		if config.For > 0 {
+			m.addPendingEventAlert(action, config.For)
+			continue
 		}
+
+		m.enqueue(ctx, action)
 	}
 }
 
 func (m *Manager) closeEventAlerts(ctx context.Context) error {
-	// TODO stream setup timeout?
-	stream, err := m.EventStreamer.Stream(ctx)
-	if err != nil {
-		return fmt.Errorf("unable to start event stream: %w", err)
-	}
-
-	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			event, err := stream.Next(ctx)
-			if err != nil {
-				return fmt.Errorf("unable to get next event: %w", err)
-			}
+	return m.streamEvents(ctx, "close", m.closeEventAlert)
+}
 
-			alerts, err := m.IssueStore.ActiveAlerts(ctx)
-			if err != nil {
-				// TODO log instead of fail?
-				return fmt.Errorf("unable to get active alerts: %w", err)
-			}
+func (m *Manager) closeEventAlert(ctx context.Context, event smee.Event) {
+	m.closeHeldEventAlerts(event)
+	m.closePendingEventAlerts(event)
 
-			for i := range alerts {
-				alert := alerts[i]
-				config, ok := m.AlertConfigs[alert.Type]
-				if !ok {
-					// TODO log that i don't know how to handle this alert
-					continue
-				}
+	alerts := append(m.active.device(event.RoomID, event.DeviceID), m.shadowActive.device(event.RoomID, event.DeviceID)...)
+	if len(alerts) == 0 {
+		return
+	}
 
-				trans := config.Close.Event
-				switch {
-				case trans == nil:
-					continue
-				case event.DeviceID != alert.Device.ID || event.RoomID != alert.Device.Room.ID:
-					continue
-				case trans.KeyMatches != nil && !trans.KeyMatches.MatchString(event.Key):
-					continue
-				case trans.KeyDoesNotMatch != nil && trans.KeyDoesNotMatch.MatchString(event.Key):
-					continue
-				case trans.ValueMatches != nil && !trans.ValueMatches.MatchString(event.Value):
-					continue
-				case trans.ValueDoesNotMatch != nil && trans.ValueDoesNotMatch.MatchString(event.Value):
-					continue
-				}
+	configs := m.alertConfigs()
+	for i := range alerts {
+		alert := alerts[i]
+		if !rules.Closes(configs, alert.Type, event, m.transitionError) {
+			continue
+		}
 
-				m.Log.Debug("Closing issue because of event", zap.Any("event", event))
+		m.Log.Debug("Closing issue because of event", zap.Any("event", event))
 
-				// close the alert
-				m.queue <- alertAction{
-					action: "close",
-					alert:  alert,
-					events: []smee.IssueEvent{
-						{
-							Type:      smee.TypeSystemMessage,
-							Timestamp: time.Now(),
-							Data:      smee.NewSystemMessage(fmt.Sprintf("AV Bot: |%v| %v alert ended (Value: %v)", event.DeviceID, alert.Type, event.Value)),
-						},
-					},
-				}
-			}
-		}
+		// close the alert
+		m.enqueue(ctx, alertAction{
+			action: "close",
+			alert:  alert,
+			events: []smee.IssueEvent{
+				{
+					Type:      smee.TypeSystemMessage,
+					Timestamp: time.Now(),
+					Data:      smee.NewSystemMessage(fmt.Sprintf("AV Bot: |%v| %v alert ended (Value: %v)", event.DeviceID, alert.Type, event.Value)),
+				},
+			},
+		})
 	}
 }
+
+// transitionError logs events that can't be evaluated against an alert
+// type's transition, so that a device reporting garbage doesn't go unnoticed
+func (m *Manager) transitionError(typ string, event smee.Event, err error) {
+	m.Log.Warn("unable to evaluate alert transition", zap.Error(err), zap.String("type", typ), zap.String("roomID", event.RoomID), zap.String("deviceID", event.DeviceID), zap.String("key", event.Key))
+}
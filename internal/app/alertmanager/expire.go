@@ -0,0 +1,90 @@
+package alertmanager
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/byuoitav/smee/internal/app/alertmanager/rules"
+	"github.com/byuoitav/smee/internal/smee"
+	"go.uber.org/zap"
+)
+
+// manageExpiredAlerts closes alerts that have been open longer than their
+// type's TTL. This is mostly for alert types that have no close transition
+// (like help requests), which would otherwise stay open until someone closes
+// the whole issue.
+func (m *Manager) manageExpiredAlerts(ctx context.Context) error {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case now := <-ticker.C:
+			m.expireAlerts(ctx, now)
+		}
+	}
+}
+
+func (m *Manager) expireAlerts(ctx context.Context, now time.Time) {
+	for typ, config := range m.alertConfigs() {
+		if config.TTL <= 0 {
+			continue
+		}
+
+		alerts, err := m.activeAlertsByType(ctx, typ)
+		if err != nil {
+			m.Log.Warn("unable to get active alerts", zap.Error(err), zap.String("type", typ))
+			continue
+		}
+
+		for _, alert := range alerts {
+			if !rules.Expired(config, alert.Start, now) || !m.claimExpiring(alert.ID) {
+				continue
+			}
+
+			m.Log.Info("Closing expired alert", zap.String("roomID", alert.Device.Room.ID), zap.String("deviceID", alert.Device.ID), zap.String("type", alert.Type), zap.Duration("ttl", config.TTL))
+
+			err := m.enqueue(ctx, alertAction{
+				action: "close",
+				alert:  alert,
+				events: []smee.IssueEvent{
+					{
+						Type:      smee.TypeSystemMessage,
+						Timestamp: now,
+						Data:      smee.NewSystemMessage(fmt.Sprintf("AV Bot: |%v| %v alert closed automatically because it was open longer than %v", alert.Device.ID, alert.Type, config.TTL)),
+					},
+				},
+			})
+			if err != nil {
+				m.Log.Warn("unable to queue close for expired alert", zap.Error(err), zap.String("roomID", alert.Device.Room.ID), zap.String("deviceID", alert.Device.ID), zap.String("type", alert.Type))
+				m.releaseExpiring(alert.ID)
+			}
+		}
+	}
+}
+
+// claimExpiring marks alertID as having a close queued by expireAlerts. It
+// returns false if one is already queued, so that each sweep doesn't queue
+// another close before the first one has run.
+func (m *Manager) claimExpiring(alertID string) bool {
+	m.expiringMu.Lock()
+	defer m.expiringMu.Unlock()
+
+	if m.expiring[alertID] {
+		return false
+	}
+
+	m.expiring[alertID] = true
+	return true
+}
+
+// releaseExpiring forgets the close queued for alertID, once it has run or couldn't be queued
+func (m *Manager) releaseExpiring(alertID string) {
+	m.expiringMu.Lock()
+	defer m.expiringMu.Unlock()
+
+	delete(m.expiring, alertID)
+}
@@ -0,0 +1,35 @@
+package alertmanager
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/byuoitav/smee/internal/smee"
+	"github.com/matryer/is"
+)
+
+func TestExpireQueuesOneClose(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	m, issues := newTestManager(map[string]smee.AlertConfig{
+		"help-request": {TTL: time.Hour},
+	})
+
+	m.runAlertAction(ctx, createAction("ITB-1101", "ITB-1101-CP1", "help-request"))
+	is.Equal(issues.created["help-request"], 1)
+
+	later := time.Now().Add(2 * time.Hour)
+
+	// a second sweep before the close runs doesn't queue it again
+	m.expireAlerts(ctx, later)
+	m.expireAlerts(ctx, later)
+	is.Equal(len(m.queue), 1)
+
+	m.runAlertAction(ctx, <-m.queue)
+	is.Equal(len(m.active.byType("help-request")), 0)
+
+	m.expireAlerts(ctx, later)
+	is.Equal(len(m.queue), 0)
+}
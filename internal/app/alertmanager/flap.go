@@ -0,0 +1,220 @@
+package alertmanager
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/byuoitav/smee/internal/smee"
+	"go.uber.org/zap"
+)
+
+// flapState tracks how often an alert has opened/closed recently
+type flapState struct {
+	// transitions are the times the alert opened/closed within the flap window
+	transitions []time.Time
+
+	flapping bool
+	since    time.Time
+	// lastChange is the last time the alert would have opened/closed
+	lastChange time.Time
+	// changes is the number of transitions that were absorbed while flapping
+	changes int
+	// open is whether the alert would be open if it weren't being held open
+	open bool
+	// closeAction is the last close action that was absorbed
+	closeAction alertAction
+}
+
+// flapConfig returns the flap detection settings for typ
+func (m *Manager) flapConfig(typ string) smee.FlapConfig {
+	m.configMu.RLock()
+	defer m.configMu.RUnlock()
+
+	if config, ok := m.AlertConfigs[typ]; ok && config.Flapping != nil {
+		return *config.Flapping
+	}
+
+	if config, ok := m.StateAlertConfigs[typ]; ok && config.Flapping != nil {
+		return *config.Flapping
+	}
+
+	return m.Flapping
+}
+
+// absorbFlapping returns true if action's alert is flapping, in which case
+// the action isn't run. The alert is held open, and the transition is only
+// counted toward the summary that is added when the alert stops flapping.
+func (m *Manager) absorbFlapping(action alertAction) bool {
+	m.flapsMu.Lock()
+	defer m.flapsMu.Unlock()
+
+	st, ok := m.flaps[keyOf(action.alert)]
+	if !ok || !st.flapping {
+		return false
+	}
+
+	open := action.action == "create"
+	if st.open != open {
+		st.open = open
+		st.changes++
+		st.lastChange = time.Now()
+	}
+
+	if !open {
+		st.closeAction = action
+	}
+
+	return true
+}
+
+// recordTransition records that action's alert was just opened (or is about
+// to be closed), and returns true if that pushed it over its flap threshold.
+// A close action that returns true should not be run; the alert is held open
+// until it stops flapping.
+func (m *Manager) recordTransition(ctx context.Context, action alertAction) bool {
+	config := m.flapConfig(action.alert.Type)
+	if config.Transitions <= 0 || config.Window <= 0 {
+		return false
+	}
+
+	key := keyOf(action.alert)
+	now := time.Now()
+
+	m.flapsMu.Lock()
+	st, ok := m.flaps[key]
+	if !ok {
+		st = &flapState{}
+		m.flaps[key] = st
+	}
+
+	st.transitions = append(pruneTransitions(st.transitions, now.Add(-config.Window)), now)
+	st.open = action.action == "create"
+	st.lastChange = now
+
+	count := len(st.transitions)
+	if count < config.Transitions {
+		m.flapsMu.Unlock()
+		return false
+	}
+
+	st.flapping = true
+	st.since = now
+	if !st.open {
+		st.closeAction = action
+	}
+	m.flapsMu.Unlock()
+
+	m.Log.Info("Alert is flapping", zap.String("roomID", key.roomID), zap.String("deviceID", key.deviceID), zap.String("type", key.typ), zap.Int("transitions", count), zap.Duration("window", config.Window))
+
+	m.addRoomIssueEvents(ctx, key.roomID, smee.IssueEvent{
+		Type:      smee.TypeSystemMessage,
+		Timestamp: now,
+		Data:      smee.NewSystemMessage(fmt.Sprintf("AV Bot: |%v| %v alert is flapping (%v changes in %v). Holding it open until it settles", key.deviceID, key.typ, count, config.Window)),
+	})
+
+	return true
+}
+
+// settledFlap is an alert that stopped flapping
+type settledFlap struct {
+	key     alertKey
+	summary smee.IssueEvent
+
+	// closeAction is set if the alert should be closed now that it has settled
+	closeAction *alertAction
+}
+
+// manageFlapping releases alerts that have stopped flapping. An alert is
+// considered settled once it hasn't changed for a full flap window.
+func (m *Manager) manageFlapping(ctx context.Context) error {
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case now := <-ticker.C:
+			for _, settled := range m.settledFlaps(now) {
+				if settled.closeAction != nil {
+					action := *settled.closeAction
+					action.events = []smee.IssueEvent{settled.summary}
+					m.enqueue(ctx, action)
+					continue
+				}
+
+				m.addRoomIssueEvents(ctx, settled.key.roomID, settled.summary)
+			}
+		}
+	}
+}
+
+// settledFlaps forgets alerts that haven't changed for a full flap window,
+// and returns the ones that had been flapping
+func (m *Manager) settledFlaps(now time.Time) []settledFlap {
+	m.flapsMu.Lock()
+	defer m.flapsMu.Unlock()
+
+	var settled []settledFlap
+	for key, st := range m.flaps {
+		if now.Sub(st.lastChange) < m.flapConfig(key.typ).Window {
+			continue
+		}
+
+		delete(m.flaps, key)
+		if !st.flapping {
+			continue
+		}
+
+		m.Log.Info("Alert stopped flapping", zap.String("roomID", key.roomID), zap.String("deviceID", key.deviceID), zap.String("type", key.typ), zap.Int("changes", st.changes), zap.Bool("open", st.open))
+
+		state := "still active"
+		if !st.open {
+			state = "ended"
+		}
+
+		flap := settledFlap{
+			key: key,
+			summary: smee.IssueEvent{
+				Type:      smee.TypeSystemMessage,
+				Timestamp: now,
+				Data:      smee.NewSystemMessage(fmt.Sprintf("AV Bot: |%v| %v alert stopped flapping after %v more changes since %v, and is %v", key.deviceID, key.typ, st.changes, st.since.Format(time.Kitchen), state)),
+			},
+		}
+
+		if !st.open {
+			action := st.closeAction
+			flap.closeAction = &action
+		}
+
+		settled = append(settled, flap)
+	}
+
+	return settled
+}
+
+// addRoomIssueEvents adds events to the active issue for roomID
+func (m *Manager) addRoomIssueEvents(ctx context.Context, roomID string, events ...smee.IssueEvent) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	issue, err := m.IssueStore.ActiveIssue(ctx, roomID)
+	if err != nil {
+		m.Log.Warn("unable to get active issue", zap.Error(err), zap.String("roomID", roomID))
+		return
+	}
+
+	if err := m.IssueStore.AddIssueEvents(ctx, issue.ID, events...); err != nil {
+		m.Log.Error("unable to add issue events", zap.Error(err), zap.String("issueID", issue.ID), zap.String("roomID", roomID))
+	}
+}
+
+func pruneTransitions(transitions []time.Time, after time.Time) []time.Time {
+	i := 0
+	for i < len(transitions) && !transitions[i].After(after) {
+		i++
+	}
+
+	return transitions[i:]
+}
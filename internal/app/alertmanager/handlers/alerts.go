@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/byuoitav/smee/internal/smee"
+	"github.com/gin-gonic/gin"
+)
+
+type manualAlert struct {
+	RoomID   string `json:"roomID"`
+	DeviceID string `json:"deviceID"`
+	Type     string `json:"type"`
+	Message  string `json:"message"`
+
+	// User is the authenticated user making the request
+	User string `json:"-"`
+}
+
+// CreateAlert queues an alert for a problem no sensor sees. The alert is
+// created asynchronously, like the alerts from events.
+func (h *Handlers) CreateAlert(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	if h.AlertManager == nil {
+		c.String(http.StatusServiceUnavailable, "the alert manager is disabled")
+		return
+	}
+
+	req, ok := bindManualAlert(c)
+	if !ok {
+		return
+	}
+
+	alert := smee.Alert{
+		Device: smee.Device{
+			ID: req.DeviceID,
+			Room: smee.Room{
+				ID: req.RoomID,
+			},
+		},
+		Type: req.Type,
+	}
+
+	err := h.AlertManager.CreateAlert(ctx, alert, req.User, req.Message)
+	switch {
+	case errors.Is(err, smee.ErrInvalidAlertType):
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	case err != nil:
+		c.String(http.StatusInternalServerError, "unable to create alert: %s", err)
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+// CloseAlert queues the active alerts matching the request to be closed
+func (h *Handlers) CloseAlert(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	if h.AlertManager == nil {
+		c.String(http.StatusServiceUnavailable, "the alert manager is disabled")
+		return
+	}
+
+	req, ok := bindManualAlert(c)
+	if !ok {
+		return
+	}
+
+	err := h.AlertManager.CloseAlert(ctx, req.RoomID, req.DeviceID, req.Type, req.User, req.Message)
+	switch {
+	case errors.Is(err, smee.ErrAlertNotFound):
+		c.String(http.StatusNotFound, err.Error())
+		return
+	case err != nil:
+		c.String(http.StatusInternalServerError, "unable to close alert: %s", err)
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+// bindManualAlert binds and validates a manual alert request from an
+// authenticated user. If the request is invalid, a response is written and
+// false is returned.
+func bindManualAlert(c *gin.Context) (manualAlert, bool) {
+	user, _ := c.Request.Context().Value("user").(string)
+	if user == "" {
+		c.String(http.StatusUnauthorized, "manual alerts require an authenticated user")
+		return manualAlert{}, false
+	}
+
+	var req manualAlert
+	if err := c.Bind(&req); err != nil {
+		c.String(http.StatusBadRequest, "unable to bind: %s", err)
+		return manualAlert{}, false
+	}
+
+	req.User = user
+
+	switch {
+	case req.RoomID == "":
+		c.String(http.StatusBadRequest, "roomID is required")
+		return manualAlert{}, false
+	case req.Type == "":
+		c.String(http.StatusBadRequest, "type is required")
+		return manualAlert{}, false
+	case req.Message == "":
+		c.String(http.StatusBadRequest, "message is required")
+		return manualAlert{}, false
+	}
+
+	return req, true
+}
+
+func (h *Handlers) PendingAlerts(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	if h.AlertManager == nil {
+		c.JSON(http.StatusOK, []smee.PendingAlert{})
+		return
+	}
+
+	pending, err := h.AlertManager.PendingAlerts(ctx)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "unable to get pending alerts: %s", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, pending)
+}
+
+type shadowAlerts struct {
+	Active   []smee.Alert       `json:"active"`
+	Timeline []smee.ShadowEvent `json:"timeline"`
+}
+
+// ShadowAlerts returns the active shadow alerts and the would-have-fired/closed
+// timeline, optionally filtered to one alert type with ?type=
+func (h *Handlers) ShadowAlerts(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	res := shadowAlerts{
+		Active:   []smee.Alert{},
+		Timeline: []smee.ShadowEvent{},
+	}
+
+	if h.ShadowStore == nil {
+		c.JSON(http.StatusOK, res)
+		return
+	}
+
+	typ := c.Query("type")
+
+	active, err := h.ShadowStore.ActiveShadowAlerts(ctx)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "unable to get active shadow alerts: %s", err)
+		return
+	}
+
+	for _, alert := range active {
+		if typ == "" || alert.Type == typ {
+			res.Active = append(res.Active, alert)
+		}
+	}
+
+	res.Timeline, err = h.ShadowStore.ShadowTimeline(ctx, typ)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "unable to get shadow timeline: %s", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"time"
 
@@ -12,14 +13,17 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// TODO something to view queue sizes
-
 type Handlers struct {
 	IssueStore       smee.IssueStore
 	IncidentStore    smee.IncidentStore
 	MaintenanceStore smee.MaintenanceStore
+	SilenceStore     smee.SilenceStore
 	IssueTypeStore   smee.IssueTypeStore
 	CouchManager     couch.CouchManager
+
+	// AlertManager and ShadowStore are nil if the alert manager is disabled
+	AlertManager smee.AlertManager
+	ShadowStore  smee.ShadowStore
 }
 
 type issue struct {
@@ -35,6 +39,7 @@ type issue struct {
 	AcknowledgedBy   string                   `json:"acknowledgedBy"`
 	AcknowledgedTime *time.Time               `json:"acknowledgedTime"`
 	Status           string                   `json:"status"`
+	ParentID         string                   `json:"parentID,omitempty"`
 }
 
 type alert struct {
@@ -46,6 +51,11 @@ type alert struct {
 	End              *time.Time  `json:"end"`
 	AcknowledgedBy   string      `json:"acknowledged_by"`
 	AcknowledgedTime *time.Time  `json:"acknowledge_time"`
+	ParentID         string      `json:"parentID,omitempty"`
+	FirstValue       string      `json:"firstValue,omitempty"`
+	LastValue        string      `json:"lastValue,omitempty"`
+	LastSeen         *time.Time  `json:"lastSeen,omitempty"`
+	Count            int         `json:"count"`
 }
 
 type issueEvent struct {
@@ -155,7 +165,7 @@ func (h *Handlers) CloseIssue(c *gin.Context) {
 	defer cancel()
 
 	issueID := c.Param("issueID")
-	iss, err := h.IssueStore.CloseAlertsForIssue(ctx, issueID)
+	iss, err := h.forLinkedIssues(ctx, issueID, h.IssueStore.CloseAlertsForIssue)
 	if err != nil {
 		c.String(http.StatusInternalServerError, "unable to close issue: %s", err)
 		return
@@ -169,7 +179,7 @@ func (h *Handlers) AcknowledgeIssue(c *gin.Context) {
 	defer cancel()
 
 	issueID := c.Param("issueID")
-	iss, err := h.IssueStore.AcknowledgeIssue(ctx, issueID)
+	iss, err := h.forLinkedIssues(ctx, issueID, h.IssueStore.AcknowledgeIssue)
 	if err != nil {
 		c.String(http.StatusInternalServerError, "unable to acknowledge issue: %s", err)
 		return
@@ -182,7 +192,7 @@ func (h *Handlers) UnacknowledgeIssue(c *gin.Context) {
 	defer cancel()
 
 	issueID := c.Param("issueID")
-	iss, err := h.IssueStore.UnacknowledgeIssue(ctx, issueID)
+	iss, err := h.forLinkedIssues(ctx, issueID, h.IssueStore.UnacknowledgeIssue)
 	if err != nil {
 		c.String(http.StatusInternalServerError, "unable to acknowledge issue: %s", err)
 		return
@@ -205,6 +215,28 @@ func (h *Handlers) SetStatus(c *gin.Context) {
 
 }
 
+// forLinkedIssues runs f on each active issue linked to issueID and then on
+// issueID, so that a building issue is acknowledged or closed with its rooms.
+// It returns the result of f on issueID.
+func (h *Handlers) forLinkedIssues(ctx context.Context, issueID string, f func(context.Context, string) (smee.Issue, error)) (smee.Issue, error) {
+	issues, err := h.IssueStore.ActiveIssues(ctx)
+	if err != nil {
+		return smee.Issue{}, fmt.Errorf("unable to get active issues: %w", err)
+	}
+
+	for _, iss := range issues {
+		if iss.ParentID != issueID {
+			continue
+		}
+
+		if _, err := f(ctx, iss.ID); err != nil {
+			return smee.Issue{}, fmt.Errorf("unable to update linked issue %s: %w", iss.ID, err)
+		}
+	}
+
+	return f(ctx, issueID)
+}
+
 // TODO maintenance
 func (h *Handlers) CreateIncidentFromIssue(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
@@ -219,7 +251,7 @@ func (h *Handlers) CreateIncidentFromIssue(c *gin.Context) {
 
 	inc := smee.Incident{
 		ShortDescription: shortDesc,
-		Caller:           "avmonit1", // using av monitoring user as caller
+		Caller:           smee.IncidentCaller,
 	}
 
 	inc, err := h.IncidentStore.CreateIncident(ctx, inc)
@@ -247,6 +279,7 @@ func convertIssue(iss smee.Issue) issue {
 		Events:         make([]issueEvent, len(iss.Events)),
 		AcknowledgedBy: iss.Acknowledged_By,
 		Status:         iss.Status,
+		ParentID:       iss.ParentID,
 	}
 
 	if !iss.Acknowledged_Time.IsZero() {
@@ -275,6 +308,15 @@ func convertIssue(iss smee.Issue) issue {
 			Type:           iss.Alerts[i].Type,
 			Start:          iss.Alerts[i].Start,
 			AcknowledgedBy: iss.Alerts[i].Acknowledged_By,
+			ParentID:       iss.Alerts[i].ParentID,
+			FirstValue:     iss.Alerts[i].FirstValue,
+			LastValue:      iss.Alerts[i].LastValue,
+			Count:          iss.Alerts[i].Count,
+		}
+
+		if !iss.Alerts[i].LastSeen.IsZero() {
+			tempLastSeen := iss.Alerts[i].LastSeen
+			alert.LastSeen = &tempLastSeen
 		}
 
 		if !iss.Alerts[i].End.IsZero() {
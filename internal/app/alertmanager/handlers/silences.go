@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/byuoitav/smee/internal/smee"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handlers) Silences(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	silences, err := h.SilenceStore.Silences(ctx)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "unable to get silences: %s", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, silences)
+}
+
+func (h *Handlers) Silence(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	silence, err := h.SilenceStore.Silence(ctx, c.Param("silenceID"))
+	switch {
+	case errors.Is(err, smee.ErrSilenceNotFound):
+		c.String(http.StatusNotFound, err.Error())
+		return
+	case err != nil:
+		c.String(http.StatusInternalServerError, "unable to get silence: %s", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, silence)
+}
+
+func (h *Handlers) CreateSilence(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	silence, ok := bindSilence(c)
+	if !ok {
+		return
+	}
+
+	silence, err := h.SilenceStore.CreateSilence(ctx, silence)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "unable to create silence: %s", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, silence)
+}
+
+func (h *Handlers) UpdateSilence(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	silence, ok := bindSilence(c)
+	if !ok {
+		return
+	}
+
+	silence.ID = c.Param("silenceID")
+
+	silence, err := h.SilenceStore.UpdateSilence(ctx, silence)
+	switch {
+	case errors.Is(err, smee.ErrSilenceNotFound):
+		c.String(http.StatusNotFound, err.Error())
+		return
+	case err != nil:
+		c.String(http.StatusInternalServerError, "unable to update silence: %s", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, silence)
+}
+
+func (h *Handlers) DeleteSilence(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	err := h.SilenceStore.DeleteSilence(ctx, c.Param("silenceID"))
+	switch {
+	case errors.Is(err, smee.ErrSilenceNotFound):
+		c.String(http.StatusNotFound, err.Error())
+		return
+	case err != nil:
+		c.String(http.StatusInternalServerError, "unable to delete silence: %s", err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// bindSilence binds and validates the silence in the request body. The
+// creator is the authenticated user, if there is one. If the silence is
+// invalid, a response is written and false is returned.
+func bindSilence(c *gin.Context) (smee.Silence, bool) {
+	var silence smee.Silence
+	if err := c.Bind(&silence); err != nil {
+		c.String(http.StatusBadRequest, "unable to bind: %s", err)
+		return smee.Silence{}, false
+	}
+
+	if user, ok := c.Request.Context().Value("user").(string); ok && user != "" {
+		silence.CreatedBy = user
+	}
+
+	if silence.Start.IsZero() {
+		silence.Start = time.Now()
+	}
+
+	if err := silence.Validate(); err != nil {
+		c.String(http.StatusBadRequest, "invalid silence: %s", err)
+		return smee.Silence{}, false
+	}
+
+	return silence, true
+}
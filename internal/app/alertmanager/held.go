@@ -0,0 +1,215 @@
+package alertmanager
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/byuoitav/smee/internal/app/alertmanager/rules"
+	"github.com/byuoitav/smee/internal/smee"
+	"go.uber.org/zap"
+)
+
+// holdReason is why a create action was held instead of run
+type holdReason string
+
+const (
+	heldForMaintenance holdReason = "maintenance"
+	heldForInhibit     holdReason = "inhibit"
+	heldForSilence     holdReason = "silence"
+)
+
+// heldAlert is a create action that was held instead of run. It is created
+// once whatever held it is over, unless the alert closes first.
+type heldAlert struct {
+	reason holdReason
+	action alertAction
+
+	// source is the type of the alert that inhibited action
+	source string
+
+	// silence is the silence that matched action
+	silence smee.Silence
+
+	// state is true if action is for a device state alert. Those aren't
+	// created on release, since the state queries recreate them if they are
+	// still happening; they only mark that the queries should be re-run.
+	state bool
+}
+
+func (h heldAlert) fields() []zap.Field {
+	fields := []zap.Field{
+		zap.String("reason", string(h.reason)),
+		zap.String("roomID", h.action.alert.Device.Room.ID),
+		zap.String("deviceID", h.action.alert.Device.ID),
+		zap.String("type", h.action.alert.Type),
+	}
+
+	if h.source != "" {
+		fields = append(fields, zap.String("source", h.source))
+	}
+
+	if h.silence.ID != "" {
+		fields = append(fields, zap.String("silenceID", h.silence.ID))
+	}
+
+	return fields
+}
+
+// message is the system message added to the alert's issue when it is created
+func (h heldAlert) message() string {
+	alert := h.action.alert
+
+	switch h.reason {
+	case heldForSilence:
+		return fmt.Sprintf("AV Bot: |%v| %v alert was silenced (%v) and is still active", alert.Device.ID, alert.Type, h.silence.Comment)
+	case heldForInhibit:
+		return fmt.Sprintf("AV Bot: |%v| %v alert was inhibited by %v and is still active", alert.Device.ID, alert.Type, h.source)
+	default:
+		return fmt.Sprintf("AV Bot: |%v| %v alert was suppressed during maintenance and is still active", alert.Device.ID, alert.Type)
+	}
+}
+
+// heldAlerts is the set of held create actions. Nothing is looked up while
+// its lock is held, so the store calls to decide what to release are made
+// against a copy of the set.
+type heldAlerts struct {
+	mu sync.Mutex
+
+	// alerts is a map of reason -> alert -> held action
+	alerts map[holdReason]map[alertKey]heldAlert
+}
+
+func (h *heldAlerts) reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.alerts = make(map[holdReason]map[alertKey]heldAlert)
+}
+
+// hold adds held to the set, replacing the action already held for the same
+// alert and reason. It returns true if the alert wasn't already held.
+func (h *heldAlerts) hold(held heldAlert) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.alerts[held.reason] == nil {
+		h.alerts[held.reason] = make(map[alertKey]heldAlert)
+	}
+
+	key := keyOf(held.action.alert)
+	_, ok := h.alerts[held.reason][key]
+	h.alerts[held.reason][key] = held
+	return !ok
+}
+
+// list returns the alerts held for reason
+func (h *heldAlerts) list(reason holdReason) []heldAlert {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	res := make([]heldAlert, 0, len(h.alerts[reason]))
+	for _, held := range h.alerts[reason] {
+		res = append(res, held)
+	}
+
+	return res
+}
+
+// release removes and returns the alerts held for reason with keys. Keys that
+// are no longer held, eg. because the alert closed, are skipped.
+func (h *heldAlerts) release(reason holdReason, keys ...alertKey) []heldAlert {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var res []heldAlert
+	for _, key := range keys {
+		held, ok := h.alerts[reason][key]
+		if !ok {
+			continue
+		}
+
+		res = append(res, held)
+		delete(h.alerts[reason], key)
+	}
+
+	return res
+}
+
+// drop removes the alerts held on event's device for which closes returns true
+func (h *heldAlerts) drop(event smee.Event, closes func(typ string) bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, alerts := range h.alerts {
+		for key := range alerts {
+			if key.roomID != event.RoomID || key.deviceID != event.DeviceID {
+				continue
+			}
+
+			if closes(key.typ) {
+				delete(alerts, key)
+			}
+		}
+	}
+}
+
+// hold records a create action that was skipped
+func (m *Manager) hold(held heldAlert) {
+	_, event := m.alertConfigs()[held.action.alert.Type]
+	held.state = !event
+
+	if !m.held.hold(held) {
+		return
+	}
+
+	if held.state {
+		m.Log.Debug("Holding state alert", held.fields()...)
+		return
+	}
+
+	m.Log.Info("Holding alert", held.fields()...)
+}
+
+// closeHeldEventAlerts forgets held alerts whose close transition matches
+// event, since they would have been closed if they had been created.
+func (m *Manager) closeHeldEventAlerts(event smee.Event) {
+	configs := m.alertConfigs()
+
+	m.held.drop(event, func(typ string) bool {
+		return rules.Closes(configs, typ, event, m.transitionError)
+	})
+}
+
+// releaseHeldAlerts creates the event alerts held for reason that held no
+// longer returns true for. It returns how many alerts were released,
+// including state alerts, which callers should re-run the state queries for.
+func (m *Manager) releaseHeldAlerts(ctx context.Context, reason holdReason, held func(context.Context, heldAlert) bool) int {
+	var keys []alertKey
+	for _, h := range m.held.list(reason) {
+		if !held(ctx, h) {
+			keys = append(keys, keyOf(h.action.alert))
+		}
+	}
+
+	released := m.held.release(reason, keys...)
+	for _, h := range released {
+		if h.state {
+			m.Log.Debug("Released state alert", h.fields()...)
+			continue
+		}
+
+		m.Log.Info("Creating released alert", h.fields()...)
+
+		action := h.action
+		action.events = append(action.events, smee.IssueEvent{
+			Type:      smee.TypeSystemMessage,
+			Timestamp: time.Now(),
+			Data:      smee.NewSystemMessage(h.message()),
+		})
+
+		m.enqueue(ctx, action)
+	}
+
+	return len(released)
+}
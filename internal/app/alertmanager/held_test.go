@@ -0,0 +1,55 @@
+package alertmanager
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/byuoitav/smee/internal/smee"
+	"github.com/matryer/is"
+)
+
+func TestHeldAlerts(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	m, _ := newTestManager(map[string]smee.AlertConfig{
+		"mic-battery": {
+			Create: smee.AlertTransition{
+				Event: &smee.AlertTransitionEvent{
+					KeyMatches:   regexp.MustCompile(`^battery$`),
+					ValueMatches: regexp.MustCompile(`^low$`),
+				},
+			},
+			Close: smee.AlertTransition{
+				Event: &smee.AlertTransitionEvent{
+					KeyMatches:   regexp.MustCompile(`^battery$`),
+					ValueMatches: regexp.MustCompile(`^ok$`),
+				},
+			},
+		},
+	})
+
+	action := createAction("ITB-1101", "ITB-1101-MIC1", "mic-battery")
+
+	m.hold(heldAlert{reason: heldForMaintenance, action: action})
+	is.Equal(len(m.held.list(heldForMaintenance)), 1)
+
+	// held alerts are forgotten if they close
+	m.closeHeldEventAlerts(smee.Event{RoomID: "ITB-1101", DeviceID: "ITB-1101-MIC1", Key: "battery", Value: "ok"})
+	is.Equal(len(m.held.list(heldForMaintenance)), 0)
+
+	m.hold(heldAlert{reason: heldForInhibit, action: action, source: "sys-offline"})
+
+	released := m.releaseHeldAlerts(ctx, heldForInhibit, func(context.Context, heldAlert) bool { return true })
+	is.Equal(released, 0)
+	is.Equal(len(m.queue), 0)
+
+	released = m.releaseHeldAlerts(ctx, heldForInhibit, func(context.Context, heldAlert) bool { return false })
+	is.Equal(released, 1)
+	is.Equal(len(m.held.list(heldForInhibit)), 0)
+
+	queued := <-m.queue
+	is.Equal(queued.alert, action.alert)
+	is.Equal(len(queued.events), 1) // system message saying why it was held
+}
@@ -0,0 +1,160 @@
+package alertmanager
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/byuoitav/smee/internal/smee"
+	"go.uber.org/zap"
+)
+
+func (m *Manager) incidentRules() map[string]smee.IncidentRule {
+	m.configMu.RLock()
+	defer m.configMu.RUnlock()
+	return m.IncidentRules
+}
+
+// incidentRequest is an incident for manageIncidents to open for alert on issue
+type incidentRequest struct {
+	issue smee.Issue
+	alert smee.Alert
+	rule  smee.IncidentRule
+}
+
+// queueAlertIncident queues an incident to be opened for a newly created
+// alert, if its type has an incident rule and the issue doesn't already have
+// an incident. It is called from the action loop, and only one incident is
+// opened per issue at a time, so a second alert on the same issue doesn't
+// open a second incident while the first is still being created.
+func (m *Manager) queueAlertIncident(issue smee.Issue, alert smee.Alert) {
+	rule, ok := m.incidentRules()[alert.Type]
+	if !ok || len(issue.Incidents) > 0 || !m.claimIncident(issue.ID) {
+		return
+	}
+
+	select {
+	case m.incidents <- incidentRequest{issue: issue, alert: alert, rule: rule}:
+	default:
+		m.releaseIncident(issue.ID)
+		m.Log.Warn("incident queue is full, not creating incident", zap.String("issueID", issue.ID), zap.String("type", alert.Type))
+	}
+}
+
+// claimIncident marks issueID as having an incident being opened. It returns
+// false if one already is, by an incident rule or an escalation.
+func (m *Manager) claimIncident(issueID string) bool {
+	m.openingMu.Lock()
+	defer m.openingMu.Unlock()
+
+	if m.opening[issueID] {
+		return false
+	}
+
+	m.opening[issueID] = true
+	return true
+}
+
+// releaseIncident forgets the incident being opened for issueID
+func (m *Manager) releaseIncident(issueID string) {
+	m.openingMu.Lock()
+	defer m.openingMu.Unlock()
+
+	delete(m.opening, issueID)
+}
+
+// manageIncidents opens the queued incidents. ServiceNow can take a while to
+// respond, so they are opened here instead of in the action loop.
+func (m *Manager) manageIncidents(ctx context.Context) error {
+	for {
+		select {
+		case req := <-m.incidents:
+			m.openAlertIncident(ctx, req)
+			m.releaseIncident(req.issue.ID)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// openAlertIncident creates and links the incident for req
+func (m *Manager) openAlertIncident(ctx context.Context, req incidentRequest) {
+	issue, alert := req.issue, req.alert
+
+	data := smee.IncidentTemplateData{
+		Room:      alert.Device.Room.ID,
+		Device:    alert.Device.ID,
+		Type:      alert.Type,
+		KBArticle: m.kbArticle(ctx, alert.Type),
+	}
+
+	var desc bytes.Buffer
+	if err := req.rule.ShortDescription.Execute(&desc, data); err != nil {
+		m.Log.Error("unable to build incident short description", zap.Error(err), zap.String("type", alert.Type))
+		return
+	}
+
+	msg := ""
+	inc, err := m.createIncident(ctx, issue, desc.String())
+	if err != nil {
+		m.Log.Warn("unable to create incident", zap.Error(err), zap.String("issueID", issue.ID), zap.String("type", alert.Type))
+		msg = fmt.Sprintf("AV Bot: |%v| unable to create incident for %v alert", alert.Device.ID, alert.Type)
+	} else {
+		m.Log.Info("Created incident", zap.String("issueID", issue.ID), zap.String("incident", inc.Name), zap.String("type", alert.Type))
+		msg = fmt.Sprintf("AV Bot: |%v| created incident %v for %v alert", alert.Device.ID, inc.Name, alert.Type)
+	}
+
+	event := smee.IssueEvent{
+		Type:      smee.TypeSystemMessage,
+		Timestamp: time.Now(),
+		Data:      smee.NewSystemMessage(msg),
+	}
+
+	if err := m.IssueStore.AddIssueEvents(ctx, issue.ID, event); err != nil {
+		m.Log.Error("unable to add issue events", zap.Error(err), zap.String("issueID", issue.ID))
+	}
+}
+
+// kbArticle returns the KB article for typ from the alert types table, or ""
+// if it doesn't have one
+func (m *Manager) kbArticle(ctx context.Context, typ string) string {
+	if m.IssueTypeStore == nil {
+		return ""
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	types, err := m.IssueTypeStore.IssueType(ctx)
+	if err != nil {
+		m.Log.Warn("unable to get alert types", zap.Error(err))
+		return ""
+	}
+
+	return types[typ].KbArticle
+}
+
+// createIncident creates an incident with shortDesc and links it to issue
+func (m *Manager) createIncident(ctx context.Context, issue smee.Issue, shortDesc string) (smee.Incident, error) {
+	if m.IncidentStore == nil {
+		return smee.Incident{}, fmt.Errorf("no incident store configured")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	inc, err := m.IncidentStore.CreateIncident(ctx, smee.Incident{
+		ShortDescription: shortDesc,
+		Caller:           smee.IncidentCaller,
+	})
+	if err != nil {
+		return smee.Incident{}, fmt.Errorf("unable to create incident: %w", err)
+	}
+
+	if _, err := m.IssueStore.LinkIncident(ctx, issue.ID, inc); err != nil {
+		return smee.Incident{}, fmt.Errorf("unable to link incident %v: %w", inc.Name, err)
+	}
+
+	return inc, nil
+}
@@ -0,0 +1,35 @@
+package alertmanager
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/byuoitav/smee/internal/smee"
+	"github.com/matryer/is"
+)
+
+func TestEscalationWaitsForRuleIncident(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	m, _ := newTestManager(nil)
+	m.IncidentRules = map[string]smee.IncidentRule{
+		"help-request": {},
+	}
+
+	issue := smee.Issue{ID: "1", Room: smee.Room{ID: "ITB-1101"}}
+	m.queueAlertIncident(issue, testAlert("ITB-1101", "ITB-1101", "help-request", time.Now()))
+	is.Equal(len(m.incidents), 1)
+
+	// the incident rule is still opening the issue's incident
+	inc, err := m.createEscalationIncident(ctx, issue, []string{"help-request"})
+	is.NoErr(err)
+	is.Equal(inc, smee.Incident{})
+
+	m.releaseIncident((<-m.incidents).issue.ID)
+
+	// without an incident store, the escalation tries and fails to open one
+	_, err = m.createEscalationIncident(ctx, issue, []string{"help-request"})
+	is.True(err != nil)
+}
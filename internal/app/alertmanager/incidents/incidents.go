@@ -56,6 +56,14 @@ func (s *Store) AddIssueEvents(ctx context.Context, id string, events ...smee.Is
 			if err := s.Client.AddInternalNote(ctx, id, v.Message); err != nil {
 				return fmt.Errorf("unable to add event %d/%d: %w", i+1, len(events), err)
 			}
+		case smee.EscalationMessage:
+			if err := s.Client.AddInternalNote(ctx, id, v.Message); err != nil {
+				return fmt.Errorf("unable to add event %d/%d: %w", i+1, len(events), err)
+			}
+		case smee.UserMessage:
+			if err := s.Client.AddInternalNote(ctx, id, fmt.Sprintf("%s: %s", v.User, v.Message)); err != nil {
+				return fmt.Errorf("unable to add event %d/%d: %w", i+1, len(events), err)
+			}
 		default:
 			// skip it
 		}
@@ -0,0 +1,130 @@
+package alertmanager
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/byuoitav/smee/internal/smee"
+	"go.uber.org/zap"
+)
+
+// deviceKey identifies a device in a room
+type deviceKey struct {
+	roomID   string
+	deviceID string
+}
+
+// alertIndex is the set of active alerts, indexed by the device they are on.
+// It is only written to by runAlertActions, so that it stays consistent with
+// the alerts the manager creates and closes.
+type alertIndex struct {
+	mu sync.RWMutex
+
+	// alerts is a map of device -> alertID -> alert
+	alerts map[deviceKey]map[string]smee.Alert
+}
+
+// reset replaces every alert in the index with alerts
+func (idx *alertIndex) reset(alerts []smee.Alert) {
+	byDevice := make(map[deviceKey]map[string]smee.Alert)
+	for _, alert := range alerts {
+		key := deviceKey{roomID: alert.Device.Room.ID, deviceID: alert.Device.ID}
+		if byDevice[key] == nil {
+			byDevice[key] = make(map[string]smee.Alert)
+		}
+
+		byDevice[key][alert.ID] = alert
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.alerts = byDevice
+}
+
+func (idx *alertIndex) add(alert smee.Alert) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if idx.alerts == nil {
+		idx.alerts = make(map[deviceKey]map[string]smee.Alert)
+	}
+
+	key := deviceKey{roomID: alert.Device.Room.ID, deviceID: alert.Device.ID}
+	if idx.alerts[key] == nil {
+		idx.alerts[key] = make(map[string]smee.Alert)
+	}
+
+	idx.alerts[key][alert.ID] = alert
+}
+
+func (idx *alertIndex) remove(alert smee.Alert) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	key := deviceKey{roomID: alert.Device.Room.ID, deviceID: alert.Device.ID}
+	delete(idx.alerts[key], alert.ID)
+	if len(idx.alerts[key]) == 0 {
+		delete(idx.alerts, key)
+	}
+}
+
+// device returns the active alerts on deviceID in roomID
+func (idx *alertIndex) device(roomID, deviceID string) []smee.Alert {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	alerts := idx.alerts[deviceKey{roomID: roomID, deviceID: deviceID}]
+	if len(alerts) == 0 {
+		return nil
+	}
+
+	res := make([]smee.Alert, 0, len(alerts))
+	for _, alert := range alerts {
+		res = append(res, alert)
+	}
+
+	return res
+}
+
+// byType returns the alerts in the index of type typ
+func (idx *alertIndex) byType(typ string) []smee.Alert {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var res []smee.Alert
+	for _, alerts := range idx.alerts {
+		for _, alert := range alerts {
+			if alert.Type == typ {
+				res = append(res, alert)
+			}
+		}
+	}
+
+	return res
+}
+
+// contains returns true if alert is in the index
+func (idx *alertIndex) contains(alert smee.Alert) bool {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	_, ok := idx.alerts[deviceKey{roomID: alert.Device.Room.ID, deviceID: alert.Device.ID}][alert.ID]
+	return ok
+}
+
+// syncActiveAlerts rebuilds the active alert index from the issue store. This
+// picks up alerts that were created or closed outside of the manager.
+func (m *Manager) syncActiveAlerts(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	alerts, err := m.IssueStore.ActiveAlerts(ctx)
+	if err != nil {
+		m.Log.Error("unable to sync active alerts", zap.Error(err))
+		return
+	}
+
+	m.active.reset(alerts)
+	setActiveMetrics(alerts)
+}
@@ -0,0 +1,79 @@
+package alertmanager
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/byuoitav/smee/internal/smee"
+	"go.uber.org/zap"
+)
+
+func (m *Manager) inhibitRules() []smee.InhibitRule {
+	m.configMu.RLock()
+	defer m.configMu.RUnlock()
+	return m.InhibitRules
+}
+
+// inhibitedBy returns the type of the active alert in alert's room that
+// inhibits alert, or "" if alert isn't inhibited.
+func (m *Manager) inhibitedBy(ctx context.Context, alert smee.Alert) string {
+	var sources []string
+	for _, rule := range m.inhibitRules() {
+		if rule.Inhibits(alert.Type) {
+			sources = append(sources, rule.Source)
+		}
+	}
+
+	if len(sources) == 0 {
+		return ""
+	}
+
+	issue, err := m.IssueStore.ActiveIssue(ctx, alert.Device.Room.ID)
+	switch {
+	case errors.Is(err, smee.ErrRoomIssueNotFound):
+		return ""
+	case err != nil:
+		m.Log.Warn("unable to get active issue to check inhibitions", zap.Error(err), zap.String("roomID", alert.Device.Room.ID))
+		return ""
+	}
+
+	for _, source := range sources {
+		for _, a := range issue.Alerts {
+			if a.Active() && a.Type == source {
+				return source
+			}
+		}
+	}
+
+	return ""
+}
+
+// isInhibitSource returns true if typ inhibits any other alert type
+func (m *Manager) isInhibitSource(typ string) bool {
+	for _, rule := range m.inhibitRules() {
+		if rule.Source == typ {
+			return true
+		}
+	}
+
+	return false
+}
+
+// manageInhibitedAlerts creates inhibited alerts that are still active once
+// the alert that was inhibiting them has closed.
+func (m *Manager) manageInhibitedAlerts(ctx context.Context) error {
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			m.releaseHeldAlerts(ctx, heldForInhibit, func(ctx context.Context, held heldAlert) bool {
+				return m.inhibitedBy(ctx, held.action.alert) != ""
+			})
+		}
+	}
+}
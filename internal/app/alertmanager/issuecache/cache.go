@@ -20,6 +20,9 @@ type Cache struct {
 	IncidentStore smee.IncidentStore
 	Log           *zap.Logger
 
+	// Notifier is optional. If set, it is told about issue lifecycle events.
+	Notifier smee.Notifier
+
 	// issues is a map of issueID to the currently active issue for that room
 	issues map[string]smee.Issue
 	// issuesMu protects issues
@@ -51,6 +54,8 @@ func (c *Cache) CreateAlert(ctx context.Context, alert smee.Alert) (smee.Issue,
 	c.issuesMu.Lock()
 	defer c.issuesMu.Unlock()
 
+	_, existed := c.activeRoomIssue(alert.Device.Room.ID)
+
 	if c.IssueStore != nil {
 		iss, err := c.IssueStore.CreateAlert(ctx, alert)
 		if err != nil {
@@ -59,6 +64,7 @@ func (c *Cache) CreateAlert(ctx context.Context, alert smee.Alert) (smee.Issue,
 
 		// update the cache
 		c.issues[iss.ID] = iss
+		c.notifyCreated(iss, alert, !existed)
 		return iss, nil
 	}
 
@@ -91,6 +97,7 @@ func (c *Cache) CreateAlert(ctx context.Context, alert smee.Alert) (smee.Issue,
 	alert.IssueID = issue.ID
 	issue.Alerts[alert.ID] = alert
 	c.issues[issue.ID] = issue
+	c.notifyCreated(issue, alert, !existed)
 	return issue, nil
 }
 
@@ -110,6 +117,7 @@ func (c *Cache) AcknowledgeIssue(ctx context.Context, issueID string) (smee.Issu
 			delete(c.issues, iss.ID)
 		}
 
+		c.notify(smee.NotifyIssueAcknowledged, iss, nil)
 		return iss, nil
 	}
 
@@ -167,6 +175,7 @@ func (c *Cache) SetIssueStatus(ctx context.Context, issueID string, status strin
 			delete(c.issues, iss.ID)
 		}
 
+		c.notify(smee.NotifyIssueStatusChanged, iss, nil)
 		return iss, nil
 	}
 
@@ -193,6 +202,7 @@ func (c *Cache) CloseAlertsForIssue(ctx context.Context, issueID string) (smee.I
 			delete(c.issues, iss.ID)
 		}
 
+		c.notifyClosed(iss)
 		return iss, nil
 	}
 
@@ -208,6 +218,7 @@ func (c *Cache) CloseAlertsForIssue(ctx context.Context, issueID string) (smee.I
 
 		issue.End = time.Now()
 		delete(c.issues, issue.ID)
+		c.notifyClosed(issue)
 	}
 
 	return issue, nil
@@ -230,6 +241,7 @@ func (c *Cache) CloseAlert(ctx context.Context, issueID, alertID string) (smee.I
 			delete(c.issues, iss.ID)
 		}
 
+		c.notifyClosed(iss)
 		return iss, nil
 	}
 
@@ -256,6 +268,7 @@ func (c *Cache) CloseAlert(ctx context.Context, issueID, alertID string) (smee.I
 
 		issue.End = time.Now()
 		delete(c.issues, issue.ID)
+		c.notifyClosed(issue)
 	}
 
 	return issue, nil
@@ -319,3 +332,96 @@ func (c *Cache) LinkIncident(ctx context.Context, issueID string, inc smee.Incid
 	c.issues[issue.ID] = issue
 	return issue, nil
 }
+
+func (c *Cache) RecordAlertOccurrence(ctx context.Context, issueID, alertID, value string, seen time.Time) (smee.Issue, error) {
+	c.issuesMu.Lock()
+	defer c.issuesMu.Unlock()
+
+	if c.IssueStore != nil {
+		iss, err := c.IssueStore.RecordAlertOccurrence(ctx, issueID, alertID, value, seen)
+		if err != nil {
+			return smee.Issue{}, fmt.Errorf("unable to record alert occurrence on substore: %w", err)
+		}
+
+		// update the cache
+		c.issues[iss.ID] = iss
+		return iss, nil
+	}
+
+	issue, ok := c.issues[issueID]
+	if !ok {
+		return smee.Issue{}, errors.New("issue does not exist")
+	}
+
+	alert, ok := issue.Alerts[alertID]
+	if !ok || !alert.Active() {
+		return smee.Issue{}, errors.New("active alert does not exist on issue")
+	}
+
+	alert.Seen(value, seen)
+	issue.Alerts[alert.ID] = alert
+	c.issues[issue.ID] = issue
+	return issue, nil
+}
+
+func (c *Cache) SetAlertParent(ctx context.Context, issueID, parentID string, alertIDs ...string) (smee.Issue, error) {
+	c.issuesMu.Lock()
+	defer c.issuesMu.Unlock()
+
+	if c.IssueStore != nil {
+		iss, err := c.IssueStore.SetAlertParent(ctx, issueID, parentID, alertIDs...)
+		if err != nil {
+			return smee.Issue{}, fmt.Errorf("unable to set alert parent on substore: %w", err)
+		}
+
+		// update the cache
+		c.issues[iss.ID] = iss
+		return iss, nil
+	}
+
+	issue, ok := c.issues[issueID]
+	if !ok {
+		return smee.Issue{}, errors.New("issue does not exist")
+	}
+
+	if _, ok := issue.Alerts[parentID]; !ok {
+		return smee.Issue{}, errors.New("parent alert does not exist on issue")
+	}
+
+	for _, alertID := range alertIDs {
+		alert, ok := issue.Alerts[alertID]
+		if !ok {
+			return smee.Issue{}, errors.New("alert does not exist on issue")
+		}
+
+		alert.ParentID = parentID
+		issue.Alerts[alert.ID] = alert
+	}
+
+	c.issues[issue.ID] = issue
+	return issue, nil
+}
+
+func (c *Cache) SetIssueParent(ctx context.Context, parentID string, issueIDs ...string) error {
+	c.issuesMu.Lock()
+	defer c.issuesMu.Unlock()
+
+	if c.IssueStore != nil {
+		if err := c.IssueStore.SetIssueParent(ctx, parentID, issueIDs...); err != nil {
+			return fmt.Errorf("unable to set issue parent on substore: %w", err)
+		}
+	}
+
+	// closed issues aren't in the cache, so only update the ones that are
+	for _, issueID := range issueIDs {
+		issue, ok := c.issues[issueID]
+		if !ok {
+			continue
+		}
+
+		issue.ParentID = parentID
+		c.issues[issue.ID] = issue
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package issuecache
+
+import (
+	"context"
+	"time"
+
+	"github.com/byuoitav/smee/internal/smee"
+	"go.uber.org/zap"
+)
+
+// notify sends a notification about issue in the background, so that a slow
+// notifier never holds up the cache. The notifier gets its own copy of issue,
+// since the cache keeps changing the cached one.
+func (c *Cache) notify(typ smee.NotificationType, issue smee.Issue, alert *smee.Alert) {
+	if c.Notifier == nil {
+		return
+	}
+
+	n := smee.Notification{
+		Type:      typ,
+		Timestamp: time.Now(),
+		Issue:     copyIssue(issue),
+		Alert:     alert,
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		if err := c.Notifier.Notify(ctx, n); err != nil {
+			c.Log.Warn("unable to send notification", zap.Error(err), zap.String("type", string(typ)), zap.String("issueID", issue.ID))
+		}
+	}()
+}
+
+// notifyCreated sends the notification for a new alert on issue. created
+// should be true if the issue was created for the alert.
+func (c *Cache) notifyCreated(issue smee.Issue, alert smee.Alert, created bool) {
+	// find the alert as stored, so that it has its ID
+	for _, a := range issue.Alerts {
+		if a.Active() && a.Type == alert.Type && a.Device.ID == alert.Device.ID {
+			alert = a
+			break
+		}
+	}
+
+	typ := smee.NotifyAlertAdded
+	if created {
+		typ = smee.NotifyIssueCreated
+	}
+
+	c.notify(typ, issue, &alert)
+}
+
+// notifyClosed sends a notification if issue was closed
+func (c *Cache) notifyClosed(issue smee.Issue) {
+	if !issue.Active() {
+		c.notify(smee.NotifyIssueClosed, issue, nil)
+	}
+}
+
+// copyIssue returns a copy of issue that shares no maps or slices with it
+func copyIssue(issue smee.Issue) smee.Issue {
+	alerts := make(map[string]smee.Alert, len(issue.Alerts))
+	for id, a := range issue.Alerts {
+		alerts[id] = a
+	}
+
+	incidents := make(map[string]smee.Incident, len(issue.Incidents))
+	for id, inc := range issue.Incidents {
+		incidents[id] = inc
+	}
+
+	issue.Alerts = alerts
+	issue.Incidents = incidents
+	issue.Events = append([]smee.IssueEvent(nil), issue.Events...)
+	return issue
+}
@@ -0,0 +1,54 @@
+package issuecache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/byuoitav/smee/internal/smee"
+	"github.com/matryer/is"
+	"go.uber.org/zap"
+)
+
+// chanNotifier sends every notification it gets on a channel
+type chanNotifier chan smee.Notification
+
+func (n chanNotifier) Notify(ctx context.Context, notif smee.Notification) error {
+	n <- notif
+	return nil
+}
+
+func TestNotifyCopiesIssue(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	notifs := make(chanNotifier)
+	c := &Cache{
+		Log:      zap.NewNop(),
+		Notifier: notifs,
+	}
+	is.NoErr(c.Sync(ctx))
+
+	alert := smee.Alert{
+		Device: smee.Device{ID: "ITB-1101-CP1", Room: smee.Room{ID: "ITB-1101"}},
+		Type:   "device-offline",
+		Start:  time.Now(),
+	}
+
+	_, err := c.CreateAlert(ctx, alert)
+	is.NoErr(err)
+
+	// add another alert to the cached issue before the first notification is read
+	alert.Device.ID = "ITB-1101-D1"
+	_, err = c.CreateAlert(ctx, alert)
+	is.NoErr(err)
+
+	first, second := <-notifs, <-notifs
+	if first.Type != smee.NotifyIssueCreated {
+		first, second = second, first
+	}
+
+	is.Equal(first.Type, smee.NotifyIssueCreated)
+	is.Equal(len(first.Issue.Alerts), 1)
+	is.Equal(len(second.Issue.Alerts), 2)
+}
@@ -0,0 +1,93 @@
+package alertmanager
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/byuoitav/smee/internal/smee"
+	"go.uber.org/zap"
+)
+
+// alertKey identifies an alert independent of the issue it is (or would be) on
+type alertKey struct {
+	roomID   string
+	deviceID string
+	typ      string
+}
+
+func keyOf(alert smee.Alert) alertKey {
+	return alertKey{
+		roomID:   alert.Device.Room.ID,
+		deviceID: alert.Device.ID,
+		typ:      alert.Type,
+	}
+}
+
+// inMaintenance returns true if roomID currently has an enabled maintenance window.
+// If maintenance info can't be found, the room is treated as not in maintenance
+// so that alerts aren't lost.
+func (m *Manager) inMaintenance(ctx context.Context, roomID string) bool {
+	if m.MaintenanceStore == nil {
+		return false
+	}
+
+	info, err := m.MaintenanceStore.RoomMaintenanceInfo(ctx, roomID)
+	switch {
+	case errors.Is(err, smee.ErrRoomIssueNotFound):
+		return false
+	case err != nil:
+		m.Log.Warn("unable to get maintenance info", zap.Error(err), zap.String("roomID", roomID))
+		return false
+	}
+
+	return info.Enabled()
+}
+
+// manageMaintenance watches for maintenance windows to end. Once a room
+// leaves maintenance, the alerts that were held while it was in maintenance
+// are created, and the device state queries are re-run so that problems that
+// are still happening surface right away.
+func (m *Manager) manageMaintenance(ctx context.Context) error {
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			m.endMaintenance(ctx)
+		}
+	}
+}
+
+// endMaintenance releases the alerts held for rooms that are no longer in
+// maintenance. State alerts aren't created from what was held, so if any
+// were held the state queries are re-run to find the ones still happening.
+func (m *Manager) endMaintenance(ctx context.Context) {
+	inMaintenance := make(map[string]bool)
+	released := m.releaseHeldAlerts(ctx, heldForMaintenance, func(ctx context.Context, held heldAlert) bool {
+		roomID := held.action.alert.Device.Room.ID
+		maint, ok := inMaintenance[roomID]
+		if !ok {
+			maint = m.inMaintenance(ctx, roomID)
+			inMaintenance[roomID] = maint
+		}
+
+		return maint
+	})
+
+	if released > 0 {
+		m.reevaluateState()
+	}
+}
+
+// reevaluateState asks manageStateAlerts to run the device state queries now
+// instead of waiting for the next tick
+func (m *Manager) reevaluateState() {
+	select {
+	case m.reevaluate <- struct{}{}:
+	default:
+	}
+}
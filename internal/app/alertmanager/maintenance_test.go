@@ -0,0 +1,64 @@
+package alertmanager
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/byuoitav/smee/internal/smee"
+	"github.com/matryer/is"
+)
+
+// matchAll is a smee.DeviceStateQuery that matches every device
+type matchAll struct{}
+
+func (matchAll) Matches(map[string]interface{}, time.Time) (bool, error) {
+	return true, nil
+}
+
+func TestMaintenanceRestoresStateAlerts(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	m, issues := newTestManager(nil)
+	m.StateAlertConfigs = map[string]smee.StateAlertConfig{
+		"sys-offline": {Query: matchAll{}},
+	}
+
+	maint := &memMaintenanceStore{}
+	is.NoErr(maint.SetMaintenanceInfo(ctx, smee.MaintenanceInfo{
+		RoomID: "ITB-1101",
+		Start:  time.Now().Add(-time.Hour),
+		End:    time.Now().Add(time.Hour),
+	}))
+	m.MaintenanceStore = maint
+
+	m.DeviceStateStore = &memStateStore{
+		matches: map[string][]smee.Device{
+			"sys-offline": {{ID: "ITB-1101-CP1", Room: smee.Room{ID: "ITB-1101"}}},
+		},
+	}
+
+	// runs the state queries and the actions they queue
+	runState := func() {
+		m.runStateQueries(ctx, m.stateAlertConfigs())
+		for len(m.queue) > 0 {
+			m.runAlertAction(ctx, <-m.queue)
+		}
+	}
+
+	runState()
+	is.Equal(issues.created["sys-offline"], 0) // suppressed during maintenance
+
+	m.endMaintenance(ctx)
+	is.Equal(len(m.reevaluate), 0) // still in maintenance
+
+	is.NoErr(maint.SetMaintenanceInfo(ctx, smee.MaintenanceInfo{RoomID: "ITB-1101"}))
+
+	m.endMaintenance(ctx)
+	is.Equal(len(m.reevaluate), 1) // state queries re-run once the window ends
+
+	<-m.reevaluate
+	runState()
+	is.Equal(issues.created["sys-offline"], 1)
+}
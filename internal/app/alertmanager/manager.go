@@ -3,8 +3,11 @@ package alertmanager
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sync"
 	"time"
 
+	"github.com/byuoitav/smee/internal/app/alertmanager/config"
 	"github.com/byuoitav/smee/internal/smee"
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
@@ -15,28 +18,152 @@ import (
 type Manager struct {
 	IssueStore       smee.IssueStore
 	MaintenanceStore smee.MaintenanceStore
+	SilenceStore     smee.SilenceStore
 	EventStreamer    smee.EventStreamer
 	DeviceStateStore smee.DeviceStateStore
 	AlertConfigs     map[string]smee.AlertConfig
 	Log              *zap.Logger
 
+	// StateAlertConfigs is a map of state query name -> config for the alerts it creates
+	StateAlertConfigs map[string]smee.StateAlertConfig
+
+	// StateSweepInterval is how often every device is run through the state
+	// queries if the DeviceStateStore is a smee.DeviceStateWatcher.
+	// Defaults to 5 minutes.
+	StateSweepInterval time.Duration
+
+	// Flapping is the default flap detection config, used by alert
+	// types that don't set their own. The zero value disables it.
+	Flapping smee.FlapConfig
+
+	// InhibitRules keep symptoms of another active alert from being created
+	InhibitRules []smee.InhibitRule
+
+	// CorrelationRules open a single alert for a room when many of its
+	// devices fail together
+	CorrelationRules []smee.CorrelationRule
+
+	// BuildingRule links the issues of rooms in a building that fail
+	// together to a building issue
+	BuildingRule smee.BuildingRule
+
+	// Escalations escalate issues that haven't been acknowledged
+	Escalations []smee.EscalationPolicy
+
+	// IncidentRules is a map of alert type -> incident to open when an
+	// alert of that type is created
+	IncidentRules map[string]smee.IncidentRule
+
+	// IncidentStore and Notifier are used by escalation steps and incident rules
+	IncidentStore smee.IncidentStore
+	Notifier      smee.NamedNotifier
+
+	// IssueTypeStore is optional. It provides the KB articles used in
+	// incident short descriptions.
+	IssueTypeStore smee.IssueTypeStore
+
+	// SelfMonitorDevice is the device that alerts about the manager
+	// itself (like the event stream being down) are created on. No
+	// alerts are created about the manager if it isn't set.
+	SelfMonitorDevice smee.Device
+
+	// StreamOutageAlertAfter is how long the event stream has to be
+	// down before an alert is created on SelfMonitorDevice
+	StreamOutageAlertAfter time.Duration
+
+	// ConfigWatcher is optional. If set, the manager's config is
+	// replaced every time the watched config file changes.
+	ConfigWatcher *config.Watcher
+
+	// ShadowStore is where alerts of types in shadow mode are written
+	// instead of the IssueStore. Shadow alerts are dropped if it isn't set.
+	ShadowStore smee.ShadowStore
+
+	// ActionStore is optional. If set, actions are stored until they have
+	// been run, so that queued actions survive a restart, and the queue
+	// never blocks the goroutines that add to it. Creates for alerts that
+	// are already active aren't stored; they only record an occurrence.
+	ActionStore smee.AlertActionStore
+
 	queue chan alertAction
+
+	// setupOnce guards setup, which is done by whichever of Run and
+	// enqueue is called first
+	setupOnce sync.Once
+
+	// overflow is signaled when a stored action didn't fit in queue
+	overflow chan struct{}
+
+	// stored tracks the stored actions that are queued or were replayed,
+	// so that an action is never run both from queue and from the store
+	stored   map[string]storedState
+	storedMu sync.Mutex
+
+	// configMu protects the fields above that are set from the config once the manager is running
+	configMu sync.RWMutex
+
+	// held is the set of event alerts that weren't created because their
+	// room was in maintenance, or they were inhibited or silenced
+	held heldAlerts
+
+	// reevaluate triggers an immediate run of the device state queries
+	reevaluate chan struct{}
+
+	// pending is the set of alerts waiting for their pending period to pass
+	pending   map[alertKey]*pendingAlert
+	pendingMu sync.Mutex
+
+	// flaps tracks how often alerts are opening/closing
+	flaps   map[alertKey]*flapState
+	flapsMu sync.Mutex
+
+	// expiring is the set of alerts that expireAlerts has queued a close for
+	expiring   map[string]bool
+	expiringMu sync.Mutex
+
+	// incidents is the queue of incidents to open for new alerts
+	incidents chan incidentRequest
+
+	// opening is the set of issues that have an incident in incidents, or
+	// being opened by manageIncidents or an escalation
+	opening   map[string]bool
+	openingMu sync.Mutex
+
+	health streamHealth
+
+	// active is the set of active alerts by device, used to find the
+	// alerts an event might close
+	active alertIndex
+
+	// shadowActive is the set of active shadow alerts by device
+	shadowActive alertIndex
+
+	// buildings is the set of active issues by building
+	buildings buildingIndex
 }
 
 type alertAction struct {
+	// id is the action's ID in the ActionStore, if it was stored
+	id string
+
 	action string
 	alert  smee.Alert
 	events []smee.IssueEvent
 }
 
 func (m *Manager) Run(ctx context.Context) error {
-	m.queue = make(chan alertAction, 1024)
+	m.setup()
+
 	group, gctx := errgroup.WithContext(ctx)
 
 	if m.IssueStore == nil {
 		return errors.New("issue store required")
 	}
 
+	if err := m.registerQueueDepth(); err != nil {
+		return fmt.Errorf("unable to register queue depth metric: %w", err)
+	}
+
 	group.Go(func() error {
 		return m.runAlertActions(gctx)
 	})
@@ -45,6 +172,12 @@ func (m *Manager) Run(ctx context.Context) error {
 		return m.manageStateAlerts(gctx)
 	})
 
+	if watcher, ok := m.DeviceStateStore.(smee.DeviceStateWatcher); ok {
+		group.Go(func() error {
+			return m.watchStateAlerts(gctx, watcher)
+		})
+	}
+
 	group.Go(func() error {
 		return m.generateEventAlerts(gctx)
 	})
@@ -53,31 +186,232 @@ func (m *Manager) Run(ctx context.Context) error {
 		return m.closeEventAlerts(gctx)
 	})
 
+	group.Go(func() error {
+		return m.manageMaintenance(gctx)
+	})
+
+	group.Go(func() error {
+		return m.managePendingEventAlerts(gctx)
+	})
+
+	group.Go(func() error {
+		return m.manageFlapping(gctx)
+	})
+
+	group.Go(func() error {
+		return m.manageExpiredAlerts(gctx)
+	})
+
+	group.Go(func() error {
+		return m.manageInhibitedAlerts(gctx)
+	})
+
+	group.Go(func() error {
+		return m.manageSilences(gctx)
+	})
+
+	group.Go(func() error {
+		return m.manageStreamOutages(gctx)
+	})
+
+	group.Go(func() error {
+		return m.manageEscalations(gctx)
+	})
+
+	group.Go(func() error {
+		return m.manageIncidents(gctx)
+	})
+
+	if m.ConfigWatcher != nil {
+		group.Go(func() error {
+			return m.ConfigWatcher.Watch(gctx, m.applyConfig)
+		})
+	}
+
 	m.Log.Info("Alert manager running")
 	return group.Wait()
 }
 
+// setup creates the queue and the state the manager keeps in memory. Only
+// the first call does anything.
+func (m *Manager) setup() {
+	m.setupOnce.Do(m.init)
+}
+
+func (m *Manager) init() {
+	m.queue = make(chan alertAction, 1024)
+	m.reevaluate = make(chan struct{}, 1)
+	m.overflow = make(chan struct{}, 1)
+
+	m.storedMu.Lock()
+	m.stored = make(map[string]storedState)
+	m.storedMu.Unlock()
+
+	m.pendingMu.Lock()
+	m.pending = make(map[alertKey]*pendingAlert)
+	m.pendingMu.Unlock()
+
+	m.flaps = make(map[alertKey]*flapState)
+	m.held.reset()
+
+	m.expiringMu.Lock()
+	m.expiring = make(map[string]bool)
+	m.expiringMu.Unlock()
+
+	m.incidents = make(chan incidentRequest, 64)
+	m.openingMu.Lock()
+	m.opening = make(map[string]bool)
+	m.openingMu.Unlock()
+
+	m.health.mu.Lock()
+	m.health.down = make(map[string]time.Time)
+	m.health.mu.Unlock()
+
+	m.buildings.reset(m.buildingRule(), nil)
+}
+
+// alertConfigs returns the current alert configs. The returned map is
+// replaced (never modified) when the config is reloaded, so it is safe
+// to range over without holding the lock.
+func (m *Manager) alertConfigs() map[string]smee.AlertConfig {
+	m.configMu.RLock()
+	defer m.configMu.RUnlock()
+	return m.AlertConfigs
+}
+
+func (m *Manager) stateAlertConfigs() map[string]smee.StateAlertConfig {
+	m.configMu.RLock()
+	defer m.configMu.RUnlock()
+	return m.StateAlertConfigs
+}
+
+// applyConfig swaps in a newly loaded config. Queued actions and active
+// alerts are left alone; alerts whose type was removed simply stop being
+// closed by events until the type is added back.
+func (m *Manager) applyConfig(cfg config.Config) {
+	configs := cfg.AlertConfigs()
+
+	m.configMu.Lock()
+	defer m.configMu.Unlock()
+
+	for typ := range m.AlertConfigs {
+		if _, ok := configs[typ]; !ok {
+			m.Log.Warn("alert type removed from config", zap.String("type", typ))
+		}
+	}
+
+	m.AlertConfigs = configs
+	m.StateAlertConfigs = cfg.StateAlertConfigs()
+	m.Flapping = cfg.FlapConfig()
+	m.InhibitRules = cfg.InhibitRules()
+	m.Escalations = cfg.EscalationPolicies()
+	m.IncidentRules = cfg.IncidentRules()
+	m.CorrelationRules = cfg.CorrelationRules()
+	m.BuildingRule = cfg.BuildingRule()
+	m.buildings.regroup(m.BuildingRule)
+}
+
 // runAlertActions ensures that actions generated by this manager
 // are run in order of their placement in the queue. this makes handling
 // issue creation/closure much simpler
 func (m *Manager) runAlertActions(ctx context.Context) error {
+	m.syncActiveAlerts(ctx)
+	m.syncShadowAlerts(ctx)
+	m.syncBuildingIssues(ctx)
+
+	// run the actions that were queued but not run before the last restart
+	m.replayAlertActions(ctx)
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
 	for {
 		select {
-		case action := <-m.queue:
-			switch action.action {
-			case "create":
-				m.createAlert(ctx, action.alert, action.events)
-			case "close":
-				m.closeAlert(ctx, action.alert, action.events)
-			default:
+		case <-ticker.C:
+			m.syncActiveAlerts(ctx)
+			m.syncBuildingIssues(ctx)
+		case <-m.overflow:
+			// run what is already queued before picking up the actions
+			// that didn't fit, so that they are run in (roughly) order
+			for len(m.queue) > 0 {
+				m.runAlertAction(ctx, <-m.queue)
 			}
+
+			m.replayAlertActions(ctx)
+		case action := <-m.queue:
+			m.runAlertAction(ctx, action)
 		case <-ctx.Done():
 			return ctx.Err()
 		}
 	}
 }
 
-func (m *Manager) createAlert(ctx context.Context, alert smee.Alert, events []smee.IssueEvent) {
+func (m *Manager) runAlertAction(ctx context.Context, action alertAction) {
+	// only remove the action from the store once it has been run,
+	// so that it is run again if the manager stops part way through
+	defer m.removeAlertAction(ctx, action)
+
+	switch action.action {
+	case "create":
+		if m.inMaintenance(ctx, action.alert.Device.Room.ID) {
+			m.hold(heldAlert{reason: heldForMaintenance, action: action})
+			return
+		}
+
+		if s, ok := m.silencedBy(ctx, action.alert); ok {
+			m.hold(heldAlert{reason: heldForSilence, action: action, silence: s})
+			return
+		}
+
+		if source := m.inhibitedBy(ctx, action.alert); source != "" {
+			m.hold(heldAlert{reason: heldForInhibit, action: action, source: source})
+			return
+		}
+
+		// flap detection is skipped because it writes to the room's issue
+		if m.isShadow(action.alert.Type) {
+			m.createShadowAlert(ctx, action)
+			return
+		}
+
+		if m.absorbFlapping(action) {
+			return
+		}
+
+		if issue, ok := m.createAlert(ctx, action.alert, action.events); ok {
+			m.recordTransition(ctx, action)
+			m.queueAlertIncident(issue, action.alert)
+			m.correlate(ctx, issue, action.alert)
+			m.correlateBuilding(ctx, issue, action.alert)
+		}
+	case "close":
+		// once it has run (even if it failed), an expired alert can be closed again
+		m.releaseExpiring(action.alert.ID)
+
+		if m.shadowActive.contains(action.alert) {
+			m.closeShadowAlert(ctx, action)
+			return
+		}
+
+		if m.absorbFlapping(action) || m.recordTransition(ctx, action) {
+			return
+		}
+
+		if issue, ok := m.closeAlert(ctx, action.alert, action.events); ok {
+			m.closeCorrelatedAlert(ctx, issue, action.alert)
+			m.closeBuildingIssue(ctx, issue)
+		}
+
+		if m.isInhibitSource(action.alert.Type) {
+			// let inhibited state alerts surface right away
+			m.reevaluateState()
+		}
+	default:
+	}
+}
+
+// createAlert returns the alert's issue and true if a new alert was created
+func (m *Manager) createAlert(ctx context.Context, alert smee.Alert, events []smee.IssueEvent) (smee.Issue, bool) {
 	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
@@ -86,37 +420,85 @@ func (m *Manager) createAlert(ctx context.Context, alert smee.Alert, events []sm
 	switch {
 	case err != nil:
 		m.Log.Error("unable to check if active alert exists", zap.Error(err), zap.String("roomID", alert.Device.Room.ID), zap.String("deviceID", alert.Device.ID), zap.String("type", alert.Type))
-		return
+		return smee.Issue{}, false
 	case exists:
-		// don't need to do anything, this alert already exists
-		// TODO maybe add the event to the issue?
-		return
+		m.recordOccurrence(ctx, alert)
+		return smee.Issue{}, false
+	}
+
+	if alert.Count == 0 {
+		// state and manual alerts don't have a value
+		alert.Seen("", alert.Start)
 	}
 
 	issue, err := m.IssueStore.CreateAlert(ctx, alert)
 	if err != nil {
 		m.Log.Error("unable to create alert", zap.Error(err), zap.String("roomID", alert.Device.Room.ID), zap.String("deviceID", alert.Device.ID), zap.String("type", alert.Type))
-		return
+		return smee.Issue{}, false
 	}
 
+	for _, a := range issue.Alerts {
+		if a.Active() && a.Type == alert.Type && a.Device.ID == alert.Device.ID {
+			m.active.add(a)
+		}
+	}
+
+	m.buildings.update(issue)
+
+	alertsCreated.WithLabelValues(alert.Type).Inc()
+
 	if err := m.IssueStore.AddIssueEvents(ctx, issue.ID, events...); err != nil {
 		m.Log.Error("unable to add issue events", zap.Error(err), zap.String("issueID", issue.ID), zap.String("roomID", issue.Room.ID))
+	}
+
+	return issue, true
+}
+
+// recordOccurrence updates the active alert matching alert with alert's
+// latest value. Only alerts created from events are updated, since state
+// and manual alerts don't have a value.
+func (m *Manager) recordOccurrence(ctx context.Context, alert smee.Alert) {
+	if alert.LastSeen.IsZero() {
+		return
+	}
+
+	for _, a := range m.active.device(alert.Device.Room.ID, alert.Device.ID) {
+		if a.Type != alert.Type {
+			continue
+		}
+
+		issue, err := m.IssueStore.RecordAlertOccurrence(ctx, a.IssueID, a.ID, alert.LastValue, alert.LastSeen)
+		if err != nil {
+			m.Log.Warn("unable to record alert occurrence", zap.Error(err), zap.String("issueID", a.IssueID), zap.String("alertID", a.ID))
+			return
+		}
+
+		if updated, ok := issue.Alerts[a.ID]; ok {
+			m.active.add(updated)
+		}
+
 		return
 	}
 }
 
-func (m *Manager) closeAlert(ctx context.Context, alert smee.Alert, events []smee.IssueEvent) {
+// closeAlert returns the alert's issue and true if the alert was closed
+func (m *Manager) closeAlert(ctx context.Context, alert smee.Alert, events []smee.IssueEvent) (smee.Issue, bool) {
 	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
 	issue, err := m.IssueStore.CloseAlert(ctx, alert.IssueID, alert.ID)
 	if err != nil {
 		m.Log.Error("unable to close alert", zap.Error(err), zap.String("issueID", alert.IssueID), zap.String("alertID", alert.ID))
-		return
+		return smee.Issue{}, false
 	}
 
+	m.active.remove(alert)
+	m.buildings.update(issue)
+	alertsClosed.WithLabelValues(alert.Type).Inc()
+
 	if err := m.IssueStore.AddIssueEvents(ctx, issue.ID, events...); err != nil {
 		m.Log.Error("unable to add issue events", zap.Error(err), zap.String("issueID", alert.IssueID), zap.String("alertID", alert.ID))
-		return
 	}
+
+	return issue, true
 }
@@ -0,0 +1,86 @@
+package alertmanager
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/byuoitav/smee/internal/smee"
+	"go.uber.org/zap"
+)
+
+// CreateAlert queues alert to be created like any other alert, so it is
+// subject to maintenance, silences, and inhibitions. If alert doesn't have
+// a device, it is created on the room itself. Only the event alert types in
+// the config can be created; state alerts would just be closed by the next
+// run of their query.
+func (m *Manager) CreateAlert(ctx context.Context, alert smee.Alert, user, msg string) error {
+	if _, ok := m.stateAlertConfigs()[alert.Type]; ok {
+		return fmt.Errorf("%w: %s alerts are created from device state", smee.ErrInvalidAlertType, alert.Type)
+	}
+
+	if _, ok := m.alertConfigs()[alert.Type]; !ok {
+		return fmt.Errorf("%w: %s is not configured", smee.ErrInvalidAlertType, alert.Type)
+	}
+
+	if alert.Device.ID == "" {
+		alert.Device.ID = alert.Device.Room.ID
+	}
+
+	now := time.Now()
+	alert.Start = now
+
+	m.Log.Info("Manual alert created", zap.String("roomID", alert.Device.Room.ID), zap.String("deviceID", alert.Device.ID), zap.String("type", alert.Type), zap.String("user", user))
+
+	return m.enqueue(ctx, alertAction{
+		action: "create",
+		alert:  alert,
+		events: []smee.IssueEvent{
+			{
+				Type:      smee.TypeUserMessage,
+				Timestamp: now,
+				Data:      smee.NewUserMessage(user, "|"+alert.Device.ID+"| "+alert.Type+" alert created: "+msg),
+			},
+		},
+	})
+}
+
+// CloseAlert queues the matching active alerts to be closed. An empty
+// deviceID closes the alert on the room itself.
+func (m *Manager) CloseAlert(ctx context.Context, roomID, deviceID, typ, user, msg string) error {
+	if deviceID == "" {
+		deviceID = roomID
+	}
+
+	var alerts []smee.Alert
+	for _, alert := range m.active.device(roomID, deviceID) {
+		if alert.Type == typ {
+			alerts = append(alerts, alert)
+		}
+	}
+
+	if len(alerts) == 0 {
+		return smee.ErrAlertNotFound
+	}
+
+	m.Log.Info("Manual alert closed", zap.String("roomID", roomID), zap.String("deviceID", deviceID), zap.String("type", typ), zap.String("user", user))
+
+	for _, alert := range alerts {
+		err := m.enqueue(ctx, alertAction{
+			action: "close",
+			alert:  alert,
+			events: []smee.IssueEvent{
+				{
+					Type:      smee.TypeUserMessage,
+					Timestamp: time.Now(),
+					Data:      smee.NewUserMessage(user, "|"+deviceID+"| "+typ+" alert closed: "+msg),
+				},
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
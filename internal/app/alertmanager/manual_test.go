@@ -0,0 +1,41 @@
+package alertmanager
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/byuoitav/smee/internal/smee"
+	"github.com/matryer/is"
+	"go.uber.org/zap"
+)
+
+func TestCreateAlertTypes(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	// not running yet
+	m := &Manager{
+		AlertConfigs: map[string]smee.AlertConfig{
+			"help-request": {},
+		},
+		StateAlertConfigs: map[string]smee.StateAlertConfig{
+			"sys-offline": {},
+		},
+		Log: zap.NewNop(),
+	}
+
+	alert := testAlert("ITB-1101", "", "help-request", time.Time{})
+
+	is.NoErr(m.CreateAlert(ctx, alert, "tech", "projector is cracked"))
+	is.Equal(len(m.queue), 1)
+
+	alert.Type = "sys-offline"
+	err := m.CreateAlert(ctx, alert, "tech", "it's off")
+	is.True(errors.Is(err, smee.ErrInvalidAlertType))
+
+	alert.Type = "broken"
+	err = m.CreateAlert(ctx, alert, "tech", "it's broken")
+	is.True(errors.Is(err, smee.ErrInvalidAlertType))
+}
@@ -0,0 +1,385 @@
+package alertmanager
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/byuoitav/smee/internal/smee"
+	"go.uber.org/zap"
+)
+
+// memIssueStore is an in-memory smee.IssueStore. Each room has at most one
+// active issue, like the postgres store.
+type memIssueStore struct {
+	mu     sync.Mutex
+	nextID int
+	issues map[string]smee.Issue
+
+	// created counts the alerts created by type
+	created map[string]int
+}
+
+func newMemIssueStore() *memIssueStore {
+	return &memIssueStore{
+		issues:  make(map[string]smee.Issue),
+		created: make(map[string]int),
+	}
+}
+
+func (s *memIssueStore) id() string {
+	s.nextID++
+	return strconv.Itoa(s.nextID)
+}
+
+// copyIssue returns issue with its own Alerts map
+func copyIssue(issue smee.Issue) smee.Issue {
+	alerts := make(map[string]smee.Alert, len(issue.Alerts))
+	for id, a := range issue.Alerts {
+		alerts[id] = a
+	}
+
+	issue.Alerts = alerts
+	return issue
+}
+
+func (s *memIssueStore) activeIssue(roomID string) (smee.Issue, bool) {
+	for _, issue := range s.issues {
+		if issue.Active() && issue.Room.ID == roomID {
+			return issue, true
+		}
+	}
+
+	return smee.Issue{}, false
+}
+
+func (s *memIssueStore) CreateAlert(ctx context.Context, alert smee.Alert) (smee.Issue, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	issue, ok := s.activeIssue(alert.Device.Room.ID)
+	if !ok {
+		issue = smee.Issue{
+			ID:     s.id(),
+			Room:   alert.Device.Room,
+			Start:  alert.Start,
+			Alerts: make(map[string]smee.Alert),
+		}
+	}
+
+	alert.ID = s.id()
+	alert.IssueID = issue.ID
+	issue.Alerts[alert.ID] = alert
+
+	s.issues[issue.ID] = issue
+	s.created[alert.Type]++
+	return copyIssue(issue), nil
+}
+
+func (s *memIssueStore) CloseAlert(ctx context.Context, issueID, alertID string) (smee.Issue, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	issue, ok := s.issues[issueID]
+	if !ok {
+		return smee.Issue{}, smee.ErrRoomIssueNotFound
+	}
+
+	alert, ok := issue.Alerts[alertID]
+	if !ok {
+		return smee.Issue{}, smee.ErrAlertNotFound
+	}
+
+	alert.End = time.Now()
+	issue.Alerts[alertID] = alert
+
+	issue.End = alert.End
+	for _, a := range issue.Alerts {
+		if a.Active() {
+			issue.End = time.Time{}
+		}
+	}
+
+	s.issues[issueID] = issue
+	return copyIssue(issue), nil
+}
+
+func (s *memIssueStore) AddIssueEvents(ctx context.Context, issueID string, events ...smee.IssueEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	issue := s.issues[issueID]
+	issue.Events = append(issue.Events, events...)
+	s.issues[issueID] = issue
+	return nil
+}
+
+func (s *memIssueStore) LinkIncident(ctx context.Context, issueID string, inc smee.Incident) (smee.Issue, error) {
+	return smee.Issue{}, errors.New("not implemented")
+}
+
+func (s *memIssueStore) SetAlertParent(ctx context.Context, issueID, parentID string, alertIDs ...string) (smee.Issue, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	issue := s.issues[issueID]
+	for _, id := range alertIDs {
+		alert := issue.Alerts[id]
+		alert.ParentID = parentID
+		issue.Alerts[id] = alert
+	}
+
+	return copyIssue(issue), nil
+}
+
+func (s *memIssueStore) RecordAlertOccurrence(ctx context.Context, issueID, alertID, value string, seen time.Time) (smee.Issue, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	issue := s.issues[issueID]
+	alert := issue.Alerts[alertID]
+	alert.Seen(value, seen)
+	issue.Alerts[alertID] = alert
+	return copyIssue(issue), nil
+}
+
+func (s *memIssueStore) SetIssueParent(ctx context.Context, parentID string, issueIDs ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range issueIDs {
+		issue := s.issues[id]
+		issue.ParentID = parentID
+		s.issues[id] = issue
+	}
+
+	return nil
+}
+
+func (s *memIssueStore) ActiveIssue(ctx context.Context, roomID string) (smee.Issue, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	issue, ok := s.activeIssue(roomID)
+	if !ok {
+		return smee.Issue{}, smee.ErrRoomIssueNotFound
+	}
+
+	return copyIssue(issue), nil
+}
+
+func (s *memIssueStore) ActiveIssues(ctx context.Context) ([]smee.Issue, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var issues []smee.Issue
+	for _, issue := range s.issues {
+		if issue.Active() {
+			issues = append(issues, copyIssue(issue))
+		}
+	}
+
+	sort.Slice(issues, func(i, j int) bool {
+		return issues[i].ID < issues[j].ID
+	})
+
+	return issues, nil
+}
+
+func (s *memIssueStore) CloseAlertsForIssue(ctx context.Context, issueID string) (smee.Issue, error) {
+	return smee.Issue{}, errors.New("not implemented")
+}
+
+func (s *memIssueStore) AcknowledgeIssue(ctx context.Context, issueID string) (smee.Issue, error) {
+	return smee.Issue{}, errors.New("not implemented")
+}
+
+func (s *memIssueStore) SetIssueStatus(ctx context.Context, issueID string, status string) (smee.Issue, error) {
+	return smee.Issue{}, errors.New("not implemented")
+}
+
+func (s *memIssueStore) UnacknowledgeIssue(ctx context.Context, issueID string) (smee.Issue, error) {
+	return smee.Issue{}, errors.New("not implemented")
+}
+
+func (s *memIssueStore) ActiveAlertExists(ctx context.Context, roomID, deviceID, typ string) (bool, error) {
+	alerts, err := s.ActiveAlertsByType(ctx, typ)
+	if err != nil {
+		return false, err
+	}
+
+	for _, a := range alerts {
+		if a.Device.Room.ID == roomID && a.Device.ID == deviceID {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (s *memIssueStore) ActiveAlerts(ctx context.Context) ([]smee.Alert, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var alerts []smee.Alert
+	for _, issue := range s.issues {
+		for _, a := range issue.Alerts {
+			if a.Active() {
+				alerts = append(alerts, a)
+			}
+		}
+	}
+
+	return alerts, nil
+}
+
+func (s *memIssueStore) ActiveAlertsByType(ctx context.Context, typ string) ([]smee.Alert, error) {
+	alerts, err := s.ActiveAlerts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []smee.Alert
+	for _, a := range alerts {
+		if a.Type == typ {
+			ret = append(ret, a)
+		}
+	}
+
+	return ret, nil
+}
+
+// memActionStore is an in-memory smee.AlertActionStore
+type memActionStore struct {
+	mu      sync.Mutex
+	nextID  int
+	actions []smee.AlertAction
+
+	// removed counts how many times each action was removed, ie. run
+	removed map[string]int
+}
+
+func (s *memActionStore) AddAlertAction(ctx context.Context, action smee.AlertAction) (smee.AlertAction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	action.ID = strconv.Itoa(s.nextID)
+	s.actions = append(s.actions, action)
+	return action, nil
+}
+
+func (s *memActionStore) AlertActions(ctx context.Context) ([]smee.AlertAction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]smee.AlertAction(nil), s.actions...), nil
+}
+
+func (s *memActionStore) RemoveAlertAction(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.removed == nil {
+		s.removed = make(map[string]int)
+	}
+	s.removed[id]++
+
+	for i, action := range s.actions {
+		if action.ID == id {
+			s.actions = append(s.actions[:i], s.actions[i+1:]...)
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// memMaintenanceStore is an in-memory smee.MaintenanceStore
+type memMaintenanceStore struct {
+	mu    sync.Mutex
+	infos map[string]smee.MaintenanceInfo
+}
+
+func (s *memMaintenanceStore) RoomsInMaintenance(ctx context.Context) (map[string]smee.MaintenanceInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res := make(map[string]smee.MaintenanceInfo)
+	for roomID, info := range s.infos {
+		if info.Enabled() {
+			res[roomID] = info
+		}
+	}
+
+	return res, nil
+}
+
+func (s *memMaintenanceStore) RoomMaintenanceInfo(ctx context.Context, roomID string) (smee.MaintenanceInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	info, ok := s.infos[roomID]
+	if !ok {
+		return smee.MaintenanceInfo{}, smee.ErrRoomIssueNotFound
+	}
+
+	return info, nil
+}
+
+func (s *memMaintenanceStore) SetMaintenanceInfo(ctx context.Context, info smee.MaintenanceInfo) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.infos == nil {
+		s.infos = make(map[string]smee.MaintenanceInfo)
+	}
+
+	s.infos[info.RoomID] = info
+	return nil
+}
+
+// memStateStore is a smee.DeviceStateStore that returns fixed query results
+type memStateStore struct {
+	mu      sync.Mutex
+	matches map[string][]smee.Device
+}
+
+func (s *memStateStore) RunAlertQueries(ctx context.Context, queries map[string]smee.DeviceStateQuery) (map[string][]smee.Device, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res := make(map[string][]smee.Device)
+	for name := range queries {
+		res[name] = s.matches[name]
+	}
+
+	return res, nil
+}
+
+// newTestManager returns a manager with in-memory stores, ready to run actions
+func newTestManager(configs map[string]smee.AlertConfig) (*Manager, *memIssueStore) {
+	issues := newMemIssueStore()
+	m := &Manager{
+		IssueStore:   issues,
+		AlertConfigs: configs,
+		Log:          zap.NewNop(),
+	}
+
+	m.setup()
+	return m, issues
+}
+
+func testAlert(roomID, deviceID, typ string, start time.Time) smee.Alert {
+	return smee.Alert{
+		Device: smee.Device{
+			ID:   deviceID,
+			Room: smee.Room{ID: roomID},
+		},
+		Type:  typ,
+		Start: start,
+	}
+}
@@ -0,0 +1,84 @@
+package alertmanager
+
+import (
+	"errors"
+
+	"github.com/byuoitav/smee/internal/smee"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	alertsCreated = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "smee",
+		Subsystem: "alertmanager",
+		Name:      "alerts_created_total",
+		Help:      "The number of alerts created, by alert type.",
+	}, []string{"type"})
+
+	alertsClosed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "smee",
+		Subsystem: "alertmanager",
+		Name:      "alerts_closed_total",
+		Help:      "The number of alerts closed, by alert type.",
+	}, []string{"type"})
+
+	activeAlerts = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "smee",
+		Subsystem: "alertmanager",
+		Name:      "active_alerts",
+		Help:      "The number of active alerts, by alert type.",
+	}, []string{"type"})
+
+	activeIssues = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "smee",
+		Subsystem: "alertmanager",
+		Name:      "active_issues",
+		Help:      "The number of issues with active alerts.",
+	})
+
+	streamOutages = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "smee",
+		Subsystem: "alertmanager",
+		Name:      "event_stream_outages_total",
+		Help:      "The number of times the event stream has gone down.",
+	})
+)
+
+// registerQueueDepth exports the length of m's action queue
+func (m *Manager) registerQueueDepth() error {
+	queue := m.queue
+	err := prometheus.Register(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: "smee",
+		Subsystem: "alertmanager",
+		Name:      "queue_depth",
+		Help:      "The number of alert actions waiting to be run.",
+	}, func() float64 {
+		return float64(len(queue))
+	}))
+
+	var already prometheus.AlreadyRegisteredError
+	if errors.As(err, &already) {
+		return nil
+	}
+
+	return err
+}
+
+// setActiveMetrics updates the active alert/issue gauges from alerts
+func setActiveMetrics(alerts []smee.Alert) {
+	types := make(map[string]int)
+	issues := make(map[string]struct{})
+
+	for _, alert := range alerts {
+		types[alert.Type]++
+		issues[alert.IssueID] = struct{}{}
+	}
+
+	activeAlerts.Reset()
+	for typ, count := range types {
+		activeAlerts.WithLabelValues(typ).Set(float64(count))
+	}
+
+	activeIssues.Set(float64(len(issues)))
+}
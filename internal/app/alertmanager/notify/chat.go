@@ -0,0 +1,32 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/byuoitav/smee/internal/smee"
+)
+
+// Chat posts a one line message to a chat incoming webhook. Slack, Google
+// Chat, and Teams all accept a body of {"text": "..."}.
+type Chat struct {
+	URL string
+
+	// Client defaults to http.DefaultClient
+	Client *http.Client
+}
+
+func (c *Chat) Notify(ctx context.Context, n smee.Notification) error {
+	body, err := json.Marshal(struct {
+		Text string `json:"text"`
+	}{
+		Text: summary(n),
+	})
+	if err != nil {
+		return fmt.Errorf("unable to marshal message: %w", err)
+	}
+
+	return post(ctx, c.Client, c.URL, nil, body)
+}
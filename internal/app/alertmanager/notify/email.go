@@ -0,0 +1,66 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/byuoitav/smee/internal/smee"
+)
+
+// Email sends each notification as a plain text email over SMTP
+type Email struct {
+	Host string
+	// Port defaults to 25
+	Port int
+
+	// Username and Password are optional. If set, PLAIN auth is used.
+	Username string
+	Password string
+
+	From string
+	To   []string
+}
+
+func (e *Email) Notify(ctx context.Context, n smee.Notification) error {
+	port := e.Port
+	if port == 0 {
+		port = 25
+	}
+
+	var auth smtp.Auth
+	if e.Username != "" {
+		auth = smtp.PlainAuth("", e.Username, e.Password, e.Host)
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", e.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(e.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", summary(n))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(details(n), "\n", "\r\n"))
+
+	// smtp.SendMail doesn't take a context, so run it in the background
+	// and give up waiting on it once ctx is done
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- smtp.SendMail(net.JoinHostPort(e.Host, strconv.Itoa(port)), auth, e.From, e.To, msg.Bytes())
+	}()
+
+	select {
+	case err := <-errCh:
+		if err != nil {
+			return fmt.Errorf("unable to send mail: %w", err)
+		}
+
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
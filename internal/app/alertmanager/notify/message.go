@@ -0,0 +1,73 @@
+package notify
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/byuoitav/smee/internal/smee"
+)
+
+// summary is a one line description of n
+func summary(n smee.Notification) string {
+	room := n.Issue.Room.ID
+
+	switch n.Type {
+	case smee.NotifyIssueCreated:
+		return fmt.Sprintf("[%s] New issue: %s", room, describeAlert(n.Alert))
+	case smee.NotifyAlertAdded:
+		return fmt.Sprintf("[%s] New alert: %s", room, describeAlert(n.Alert))
+	case smee.NotifyIssueAcknowledged:
+		return fmt.Sprintf("[%s] Issue acknowledged", room)
+	case smee.NotifyIssueClosed:
+		return fmt.Sprintf("[%s] Issue closed", room)
+	case smee.NotifyIssueStatusChanged:
+		return fmt.Sprintf("[%s] Issue status changed to %q", room, n.Issue.Status)
+	case smee.NotifyIssueEscalated:
+		return fmt.Sprintf("[%s] Escalated: %s", room, n.Message)
+	default:
+		return fmt.Sprintf("[%s] %s", room, n.Type)
+	}
+}
+
+// details is a multi line description of n's issue
+func details(n smee.Notification) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Room: %s\n", n.Issue.Room.ID)
+	fmt.Fprintf(&b, "Issue: %s\n", n.Issue.ID)
+	fmt.Fprintf(&b, "Started: %s\n", n.Issue.Start.Format("Jan 2 3:04 PM"))
+
+	if n.Issue.Status != "" {
+		fmt.Fprintf(&b, "Status: %s\n", n.Issue.Status)
+	}
+
+	var alerts []smee.Alert
+	for _, alert := range n.Issue.Alerts {
+		if alert.Active() {
+			alerts = append(alerts, alert)
+		}
+	}
+
+	sort.Slice(alerts, func(i, j int) bool {
+		return alerts[i].Start.Before(alerts[j].Start)
+	})
+
+	if len(alerts) > 0 {
+		b.WriteString("\nActive alerts:\n")
+	}
+
+	for i := range alerts {
+		fmt.Fprintf(&b, "  - %s\n", describeAlert(&alerts[i]))
+	}
+
+	return b.String()
+}
+
+func describeAlert(alert *smee.Alert) string {
+	if alert == nil {
+		return "unknown alert"
+	}
+
+	return fmt.Sprintf("%s on %s", alert.Type, alert.Device.ID)
+}
@@ -0,0 +1,190 @@
+// Package notify sends issue lifecycle notifications to webhooks, email,
+// and chat, routed by room and alert type.
+package notify
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/byuoitav/smee/internal/app/alertmanager/config"
+	"github.com/byuoitav/smee/internal/smee"
+	"go.uber.org/zap"
+)
+
+// Route sends notifications that match all of its filters to Notifier.
+// An empty filter matches everything.
+type Route struct {
+	Name     string
+	Notifier smee.Notifier
+
+	RoomPrefixes []string
+	AlertTypes   []string
+	Events       []smee.NotificationType
+}
+
+// Matches returns true if n should be sent to r's Notifier
+func (r Route) Matches(n smee.Notification) bool {
+	if len(r.Events) > 0 && !containsType(r.Events, n.Type) {
+		return false
+	}
+
+	if len(r.RoomPrefixes) > 0 {
+		matched := false
+		for _, prefix := range r.RoomPrefixes {
+			if strings.HasPrefix(n.Issue.Room.ID, prefix) {
+				matched = true
+				break
+			}
+		}
+
+		if !matched {
+			return false
+		}
+	}
+
+	if len(r.AlertTypes) > 0 {
+		for _, typ := range n.AlertTypes() {
+			if contains(r.AlertTypes, typ) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return true
+}
+
+// Router is a smee.Notifier that sends each notification to every route it
+// matches. It is also a smee.NamedNotifier for its named notifiers.
+type Router struct {
+	Log *zap.Logger
+
+	mu        sync.RWMutex
+	notifiers map[string]smee.Notifier
+	routes    []Route
+}
+
+// Set replaces r's notifiers and routes
+func (r *Router) Set(notifiers map[string]smee.Notifier, routes []Route) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.notifiers = notifiers
+	r.routes = routes
+}
+
+// NotifyNamed sends n to the notifier called name, regardless of routes
+func (r *Router) NotifyNamed(ctx context.Context, name string, n smee.Notification) error {
+	r.mu.RLock()
+	notifier, ok := r.notifiers[name]
+	r.mu.RUnlock()
+
+	if !ok {
+		return fmt.Errorf("unknown notifier %q", name)
+	}
+
+	r.Log.Debug("Sending notification", zap.String("notifier", name), zap.String("type", string(n.Type)), zap.String("issueID", n.Issue.ID))
+	return notifier.Notify(ctx, n)
+}
+
+// Notify sends n to every matching route. Every route is tried even if one
+// fails; the returned error describes every failure.
+func (r *Router) Notify(ctx context.Context, n smee.Notification) error {
+	r.mu.RLock()
+	routes := r.routes
+	r.mu.RUnlock()
+
+	var errs []string
+	for _, route := range routes {
+		if !route.Matches(n) {
+			continue
+		}
+
+		r.Log.Debug("Sending notification", zap.String("route", route.Name), zap.String("type", string(n.Type)), zap.String("issueID", n.Issue.ID))
+
+		if err := route.Notifier.Notify(ctx, n); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", route.Name, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("unable to send notification to %d route(s): %s", len(errs), strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+// FromConfig builds the notifiers and notification routes in cfg
+func FromConfig(cfg config.Config) (map[string]smee.Notifier, []Route, error) {
+	notifiers := make(map[string]smee.Notifier)
+	for name, n := range cfg.Notifiers {
+		switch {
+		case n.Webhook != nil:
+			headers := make(map[string]string)
+			for k, v := range n.Webhook.Headers {
+				headers[k] = os.ExpandEnv(v)
+			}
+
+			notifiers[name] = &Webhook{
+				URL:     os.ExpandEnv(n.Webhook.URL),
+				Headers: headers,
+			}
+		case n.Email != nil:
+			notifiers[name] = &Email{
+				Host:     n.Email.Host,
+				Port:     n.Email.Port,
+				Username: os.ExpandEnv(n.Email.Username),
+				Password: os.ExpandEnv(n.Email.Password),
+				From:     n.Email.From,
+				To:       n.Email.To,
+			}
+		case n.Chat != nil:
+			notifiers[name] = &Chat{
+				URL: os.ExpandEnv(n.Chat.URL),
+			}
+		default:
+			return nil, nil, fmt.Errorf("notifier %q has no type", name)
+		}
+	}
+
+	var routes []Route
+	for i, route := range cfg.Notifications {
+		notifier, ok := notifiers[route.Notifier]
+		if !ok {
+			return nil, nil, fmt.Errorf("notifications[%d]: unknown notifier %q", i, route.Notifier)
+		}
+
+		routes = append(routes, Route{
+			Name:         route.Notifier,
+			Notifier:     notifier,
+			RoomPrefixes: route.RoomPrefixes,
+			AlertTypes:   route.AlertTypes,
+			Events:       route.Events,
+		})
+	}
+
+	return notifiers, routes, nil
+}
+
+func contains(list []string, s string) bool {
+	for i := range list {
+		if list[i] == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsType(list []smee.NotificationType, typ smee.NotificationType) bool {
+	for i := range list {
+		if list[i] == typ {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,45 @@
+package notify
+
+import (
+	"testing"
+
+	"github.com/byuoitav/smee/internal/smee"
+	"github.com/matryer/is"
+)
+
+func TestRouteMatches(t *testing.T) {
+	is := is.New(t)
+
+	route := Route{
+		RoomPrefixes: []string{"ITB-"},
+		AlertTypes:   []string{"help-request"},
+		Events:       []smee.NotificationType{smee.NotifyIssueCreated, smee.NotifyAlertAdded},
+	}
+
+	help := &smee.Alert{Type: "help-request"}
+	issue := smee.Issue{
+		Room: smee.Room{ID: "ITB-1101"},
+		Alerts: map[string]smee.Alert{
+			"1": {Type: "device-offline"},
+		},
+	}
+
+	is.True(route.Matches(smee.Notification{Type: smee.NotifyAlertAdded, Issue: issue, Alert: help}))
+
+	// wrong alert type
+	is.True(!route.Matches(smee.Notification{Type: smee.NotifyAlertAdded, Issue: issue, Alert: &smee.Alert{Type: "device-offline"}}))
+
+	// wrong room
+	other := issue
+	other.Room.ID = "JFSB-B100"
+	is.True(!route.Matches(smee.Notification{Type: smee.NotifyAlertAdded, Issue: other, Alert: help}))
+
+	// wrong event
+	is.True(!route.Matches(smee.Notification{Type: smee.NotifyIssueClosed, Issue: issue}))
+
+	// issue level notifications match on any alert on the issue
+	route.Events = nil
+	is.True(!route.Matches(smee.Notification{Type: smee.NotifyIssueClosed, Issue: issue}))
+	issue.Alerts["2"] = smee.Alert{Type: "help-request"}
+	is.True(route.Matches(smee.Notification{Type: smee.NotifyIssueClosed, Issue: issue}))
+}
@@ -0,0 +1,57 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/byuoitav/smee/internal/smee"
+)
+
+// Webhook POSTs each notification as JSON to URL
+type Webhook struct {
+	URL     string
+	Headers map[string]string
+
+	// Client defaults to http.DefaultClient
+	Client *http.Client
+}
+
+func (w *Webhook) Notify(ctx context.Context, n smee.Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return fmt.Errorf("unable to marshal notification: %w", err)
+	}
+
+	return post(ctx, w.Client, w.URL, w.Headers, body)
+}
+
+func post(ctx context.Context, client *http.Client, url string, headers map[string]string, body []byte) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("unable to build request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%v response", resp.StatusCode)
+	}
+
+	return nil
+}
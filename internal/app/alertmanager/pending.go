@@ -0,0 +1,190 @@
+package alertmanager
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/byuoitav/smee/internal/app/alertmanager/rules"
+	"github.com/byuoitav/smee/internal/smee"
+	"go.uber.org/zap"
+)
+
+// pendingAlert is a create action that is waiting for its alert type's
+// pending period to pass before it is queued
+type pendingAlert struct {
+	action alertAction
+	since  time.Time
+
+	// firesAt is set for event alerts
+	firesAt time.Time
+
+	// scans/scansRequired are set for state alerts
+	scans         int
+	scansRequired int
+}
+
+// addPendingEventAlert holds action until its condition has been true for
+// the given duration. Matching events that arrive while an alert is already
+// pending don't restart the clock, but are counted on the pending alert.
+func (m *Manager) addPendingEventAlert(action alertAction, dur time.Duration) {
+	m.pendingMu.Lock()
+	defer m.pendingMu.Unlock()
+
+	key := keyOf(action.alert)
+	if p, ok := m.pending[key]; ok {
+		p.action.alert.Seen(action.alert.LastValue, action.alert.LastSeen)
+		return
+	}
+
+	now := time.Now()
+	m.pending[key] = &pendingAlert{
+		action:  action,
+		since:   now,
+		firesAt: now.Add(dur),
+	}
+
+	m.Log.Debug("Alert pending", zap.String("roomID", key.roomID), zap.String("deviceID", key.deviceID), zap.String("type", key.typ), zap.Duration("for", dur))
+}
+
+// closePendingEventAlerts drops pending alerts whose close transition matches
+// event, because their condition didn't hold for the pending period.
+func (m *Manager) closePendingEventAlerts(event smee.Event) {
+	configs := m.alertConfigs()
+
+	m.pendingMu.Lock()
+	defer m.pendingMu.Unlock()
+
+	for key, p := range m.pending {
+		if p.firesAt.IsZero() || key.roomID != event.RoomID || key.deviceID != event.DeviceID {
+			continue
+		}
+
+		if !rules.Closes(configs, key.typ, event, m.transitionError) {
+			continue
+		}
+
+		m.Log.Debug("Pending alert cleared before it fired", zap.String("roomID", key.roomID), zap.String("deviceID", key.deviceID), zap.String("type", key.typ))
+		delete(m.pending, key)
+	}
+}
+
+// managePendingEventAlerts queues the create actions of event alerts whose
+// pending period has passed
+func (m *Manager) managePendingEventAlerts(ctx context.Context) error {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case now := <-ticker.C:
+			for _, action := range m.firedEventAlerts(now) {
+				m.enqueue(ctx, action)
+			}
+		}
+	}
+}
+
+func (m *Manager) firedEventAlerts(now time.Time) []alertAction {
+	m.pendingMu.Lock()
+	defer m.pendingMu.Unlock()
+
+	var fired []alertAction
+	for key, p := range m.pending {
+		if p.firesAt.IsZero() || now.Before(p.firesAt) {
+			continue
+		}
+
+		fired = append(fired, p.action)
+		delete(m.pending, key)
+	}
+
+	return fired
+}
+
+// pendingStateAlert records that action's device matched its state query on
+// this run, and returns true once it has matched on enough consecutive runs
+// for the alert to be created.
+func (m *Manager) pendingStateAlert(action alertAction, required int) bool {
+	if required <= 1 {
+		return true
+	}
+
+	m.pendingMu.Lock()
+	defer m.pendingMu.Unlock()
+
+	key := keyOf(action.alert)
+	p, ok := m.pending[key]
+	if !ok {
+		p = &pendingAlert{
+			action: action,
+			since:  time.Now(),
+		}
+
+		m.pending[key] = p
+	}
+
+	p.scans++
+	p.scansRequired = required
+
+	if p.scans < required {
+		return false
+	}
+
+	delete(m.pending, key)
+	return true
+}
+
+// resetPendingStateAlerts drops pending state alerts of the types in configs
+// for devices that didn't match their query on the latest run. seen is the
+// set of alerts that did.
+func (m *Manager) resetPendingStateAlerts(configs map[string]smee.StateAlertConfig, seen map[alertKey]bool) {
+	m.pendingMu.Lock()
+	defer m.pendingMu.Unlock()
+
+	for key, p := range m.pending {
+		if _, ok := configs[key.typ]; !ok {
+			continue
+		}
+
+		if p.firesAt.IsZero() && !seen[key] {
+			delete(m.pending, key)
+		}
+	}
+}
+
+// dropPendingStateAlert drops the pending state alert for key, because its
+// device no longer matches the query
+func (m *Manager) dropPendingStateAlert(key alertKey) {
+	m.pendingMu.Lock()
+	defer m.pendingMu.Unlock()
+
+	if p, ok := m.pending[key]; ok && p.firesAt.IsZero() {
+		delete(m.pending, key)
+	}
+}
+
+// PendingAlerts returns the alerts that are waiting for their pending period to pass
+func (m *Manager) PendingAlerts(ctx context.Context) ([]smee.PendingAlert, error) {
+	m.pendingMu.Lock()
+	defer m.pendingMu.Unlock()
+
+	res := []smee.PendingAlert{}
+	for _, p := range m.pending {
+		res = append(res, smee.PendingAlert{
+			Alert:         p.action.alert,
+			Since:         p.since,
+			FiresAt:       p.firesAt,
+			Scans:         p.scans,
+			ScansRequired: p.scansRequired,
+		})
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Since.Before(res[j].Since)
+	})
+
+	return res, nil
+}
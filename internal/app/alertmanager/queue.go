@@ -0,0 +1,191 @@
+package alertmanager
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/byuoitav/smee/internal/smee"
+	"go.uber.org/zap"
+)
+
+// storedState is where a stored action is in the manager
+type storedState int
+
+const (
+	// storedQueued actions are in the queue or being run from it
+	storedQueued storedState = iota + 1
+
+	// storedReplayed actions were run by replayAlertActions before
+	// the goroutine that stored them got to queue them
+	storedReplayed
+)
+
+// enqueue adds action to the queue. If the manager has an ActionStore and
+// action is durable, the action is stored first, and enqueue never blocks:
+// an action that doesn't fit in the queue is picked up from the store by
+// runAlertActions instead. Otherwise enqueue blocks until the action fits in
+// the queue or ctx is done, returning ctx's error if it didn't fit.
+func (m *Manager) enqueue(ctx context.Context, action alertAction) error {
+	m.setup()
+
+	if m.ActionStore == nil || !m.durable(action) {
+		return m.send(ctx, action)
+	}
+
+	sctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	stored, err := m.ActionStore.AddAlertAction(sctx, smee.AlertAction{
+		Action:  smee.AlertActionType(action.action),
+		Alert:   action.alert,
+		Events:  action.events,
+		Created: time.Now(),
+	})
+	if err != nil {
+		// still run the action, it just won't survive a restart
+		m.Log.Error("unable to store alert action", zap.Error(err), zap.String("action", action.action), zap.String("roomID", action.alert.Device.Room.ID), zap.String("deviceID", action.alert.Device.ID), zap.String("type", action.alert.Type))
+		return m.send(ctx, action)
+	}
+
+	action.id = stored.ID
+	if !m.claimStoredAction(action.id) {
+		// a replay of the store already ran it
+		return nil
+	}
+
+	select {
+	case m.queue <- action:
+	default:
+		m.releaseStoredAction(action.id)
+		m.Log.Warn("alert action queue is full, action will be run from the store", zap.String("actionID", action.id))
+
+		select {
+		case m.overflow <- struct{}{}:
+		default:
+		}
+	}
+
+	return nil
+}
+
+// durable returns true if action needs to be stored to survive a restart.
+// Most events that create an alert are for alerts that are already active,
+// and those only record another occurrence, so they aren't worth a write to
+// the store for every event.
+func (m *Manager) durable(action alertAction) bool {
+	if action.action != "create" {
+		return true
+	}
+
+	alert := action.alert
+	for _, a := range append(m.active.device(alert.Device.Room.ID, alert.Device.ID), m.shadowActive.device(alert.Device.Room.ID, alert.Device.ID)...) {
+		if a.Type == alert.Type {
+			return false
+		}
+	}
+
+	return true
+}
+
+// send blocks until action is in the queue or ctx is done
+func (m *Manager) send(ctx context.Context, action alertAction) error {
+	select {
+	case m.queue <- action:
+		return nil
+	case <-ctx.Done():
+		m.Log.Warn("dropping alert action", zap.Error(ctx.Err()), zap.String("action", action.action), zap.String("roomID", action.alert.Device.Room.ID), zap.String("deviceID", action.alert.Device.ID), zap.String("type", action.alert.Type))
+		return fmt.Errorf("unable to queue alert action: %w", ctx.Err())
+	}
+}
+
+// claimStoredAction marks the stored action id as queued. It returns false
+// if id was already run by replayAlertActions.
+func (m *Manager) claimStoredAction(id string) bool {
+	m.storedMu.Lock()
+	defer m.storedMu.Unlock()
+
+	if m.stored[id] == storedReplayed {
+		delete(m.stored, id)
+		return false
+	}
+
+	m.stored[id] = storedQueued
+	return true
+}
+
+// releaseStoredAction forgets the queued action id, either because it has
+// been run or because it didn't fit in the queue and is left to a replay
+func (m *Manager) releaseStoredAction(id string) {
+	m.storedMu.Lock()
+	defer m.storedMu.Unlock()
+
+	if m.stored[id] == storedQueued {
+		delete(m.stored, id)
+	}
+}
+
+// replayAlertActions runs every action in the ActionStore that isn't
+// already queued
+func (m *Manager) replayAlertActions(ctx context.Context) {
+	if m.ActionStore == nil {
+		return
+	}
+
+	sctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	actions, err := m.ActionStore.AlertActions(sctx)
+	if err != nil {
+		m.Log.Error("unable to get stored alert actions", zap.Error(err))
+		return
+	}
+
+	m.storedMu.Lock()
+	// actions replayed last time have either been claimed by now or were
+	// stored before the manager started, so they won't be queued again
+	for id, state := range m.stored {
+		if state == storedReplayed {
+			delete(m.stored, id)
+		}
+	}
+
+	var replay []smee.AlertAction
+	for _, action := range actions {
+		if _, ok := m.stored[action.ID]; ok {
+			continue
+		}
+
+		m.stored[action.ID] = storedReplayed
+		replay = append(replay, action)
+	}
+	m.storedMu.Unlock()
+
+	if len(replay) > 0 {
+		m.Log.Info("Running stored alert actions", zap.Int("count", len(replay)))
+	}
+
+	for _, action := range replay {
+		m.runAlertAction(ctx, alertAction{
+			id:     action.ID,
+			action: string(action.Action),
+			alert:  action.Alert,
+			events: action.Events,
+		})
+	}
+}
+
+// removeAlertAction removes action from the ActionStore once it has been run
+func (m *Manager) removeAlertAction(ctx context.Context, action alertAction) {
+	if m.ActionStore == nil || action.id == "" {
+		return
+	}
+	defer m.releaseStoredAction(action.id)
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	if err := m.ActionStore.RemoveAlertAction(ctx, action.id); err != nil {
+		m.Log.Error("unable to remove alert action", zap.Error(err), zap.String("actionID", action.id))
+	}
+}
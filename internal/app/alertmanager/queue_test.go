@@ -0,0 +1,100 @@
+package alertmanager
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/byuoitav/smee/internal/smee"
+	"github.com/matryer/is"
+)
+
+func createAction(roomID, deviceID, typ string) alertAction {
+	return alertAction{
+		action: "create",
+		alert:  testAlert(roomID, deviceID, typ, time.Now()),
+	}
+}
+
+func TestReplaySkipsQueuedActions(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	m, issues := newTestManager(nil)
+	actions := &memActionStore{}
+	m.ActionStore = actions
+
+	m.enqueue(ctx, createAction("ITB-1101", "ITB-1101-CP1", "device-offline"))
+	is.Equal(len(m.queue), 1)
+
+	// the queued action is left for the queue
+	m.replayAlertActions(ctx)
+	is.Equal(issues.created["device-offline"], 0)
+
+	m.runAlertAction(ctx, <-m.queue)
+	is.Equal(issues.created["device-offline"], 1)
+
+	m.replayAlertActions(ctx)
+	is.Equal(actions.removed, map[string]int{"1": 1})
+}
+
+func TestReplayRunsOverflow(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	m, issues := newTestManager(nil)
+	actions := &memActionStore{}
+	m.ActionStore = actions
+	m.queue = make(chan alertAction)
+
+	m.enqueue(ctx, createAction("ITB-1101", "ITB-1101-CP1", "device-offline"))
+
+	select {
+	case <-m.overflow:
+	default:
+		t.Fatal("overflow should be signaled")
+	}
+
+	m.replayAlertActions(ctx)
+	is.Equal(issues.created["device-offline"], 1)
+	is.Equal(len(actions.actions), 0)
+
+	// an action replayed before the goroutine that stored it queued it
+	stored, err := actions.AddAlertAction(ctx, smee.AlertAction{Action: "create", Alert: testAlert("ITB-1101", "ITB-1101-D1", "device-offline", time.Now())})
+	is.NoErr(err)
+
+	m.replayAlertActions(ctx)
+	is.True(!m.claimStoredAction(stored.ID))
+	is.Equal(actions.removed, map[string]int{"1": 1, "2": 1})
+}
+
+func TestEnqueueHonorsContext(t *testing.T) {
+	m, _ := newTestManager(nil)
+	m.queue = make(chan alertAction)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	// returns once ctx is done, even though nothing reads the queue
+	m.enqueue(ctx, createAction("ITB-1101", "ITB-1101-CP1", "device-offline"))
+}
+
+func TestEnqueueStoresNewAlertsOnly(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	m, _ := newTestManager(nil)
+	actions := &memActionStore{}
+	m.ActionStore = actions
+
+	m.runAlertAction(ctx, createAction("ITB-1101", "ITB-1101-CP1", "device-offline"))
+
+	// another occurrence of an active alert isn't stored
+	is.NoErr(m.enqueue(ctx, createAction("ITB-1101", "ITB-1101-CP1", "device-offline")))
+	is.Equal(len(actions.actions), 0)
+	is.Equal(len(m.queue), 1)
+
+	is.NoErr(m.enqueue(ctx, createAction("ITB-1101", "ITB-1101-D1", "device-offline")))
+	is.Equal(len(actions.actions), 1)
+	is.Equal(len(m.queue), 2)
+}
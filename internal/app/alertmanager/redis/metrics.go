@@ -0,0 +1,28 @@
+package redis
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var queryDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+	Namespace: "smee",
+	Subsystem: "redis",
+	Name:      "alert_queries_duration_seconds",
+	Help:      "How long it takes to run the device state alert queries.",
+	Buckets:   prometheus.ExponentialBuckets(0.05, 2, 10),
+})
+
+var keyspaceNotifications = promauto.NewCounter(prometheus.CounterOpts{
+	Namespace: "smee",
+	Subsystem: "redis",
+	Name:      "keyspace_notifications_total",
+	Help:      "The number of device state changes received from redis.",
+})
+
+var watchedTimers = promauto.NewGauge(prometheus.GaugeOpts{
+	Namespace: "smee",
+	Subsystem: "redis",
+	Name:      "scheduled_timers",
+	Help:      "The number of devices scheduled to have their time-based queries run again.",
+})
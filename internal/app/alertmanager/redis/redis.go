@@ -4,9 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/byuoitav/smee/internal/smee"
 	"github.com/go-redis/redis/v8"
+	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
 )
 
@@ -14,7 +16,6 @@ type StateStore struct {
 	Log *zap.Logger
 
 	rdb            *redis.Client
-	queries        map[string]query
 	queryBatchSize int
 }
 
@@ -30,48 +31,43 @@ func New(ctx context.Context, redisURL string) (*StateStore, error) {
 	}
 
 	return &StateStore{
+		Log:            zap.NewNop(),
 		rdb:            rdb,
-		queries:        defaultQueries(),
 		queryBatchSize: 128,
 	}, nil
 }
 
-func (s *StateStore) RunAlertQueries(ctx context.Context) (map[string][]smee.Device, error) {
-	res := make(map[string][]smee.Device)
-	runQueries := func(keys []string) error {
-		vals, err := s.rdb.MGet(ctx, keys...).Result()
-		if err != nil {
-			return fmt.Errorf("unable to mget: %w", err)
-		}
+func (s *StateStore) RunAlertQueries(ctx context.Context, queries map[string]smee.DeviceStateQuery) (map[string][]smee.Device, error) {
+	timer := prometheus.NewTimer(queryDuration)
+	defer timer.ObserveDuration()
 
-		for i, key := range keys {
-			switch val := vals[i].(type) {
-			case string:
-				// unmarshal type
-				var dev device
-				if err := json.Unmarshal([]byte(val), &dev); err != nil {
-					// TODO log error
-					s.Log.Warn("invalid device in redis", zap.String("key", key))
-					continue
-				}
-
-				for qName, q := range s.queries {
-					if q(key, dev) {
-						res[qName] = append(res[qName], smee.Device{
-							ID: dev.DeviceID,
-							Room: smee.Room{
-								ID: dev.RoomID,
-							},
-						})
-					}
-				}
-			default:
-				// TODO log error
-				fmt.Printf("%s (%T): %+v\n", key, val, val)
-			}
+	// every query has an entry, so that a query without any matches
+	// closes the alerts it opened
+	res := make(map[string][]smee.Device, len(queries))
+	for name := range queries {
+		res[name] = nil
+	}
+	now := time.Now()
+
+	err := s.scan(ctx, func(key string, state map[string]interface{}) {
+		upd := runQueries(s.Log, queries, state, now)
+		for qName := range upd.Matches {
+			res[qName] = append(res[qName], upd.Device)
 		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to run queries: %w", err)
+	}
+
+	return res, nil
+}
 
-		return nil
+// scan calls f with the state of every device in redis
+func (s *StateStore) scan(ctx context.Context, f func(key string, state map[string]interface{})) error {
+	found := func(key string, state map[string]interface{}) {
+		if state != nil {
+			f(key, state)
+		}
 	}
 
 	var keyBatch []string
@@ -81,13 +77,78 @@ func (s *StateStore) RunAlertQueries(ctx context.Context) (map[string][]smee.Dev
 		keyBatch = append(keyBatch, iter.Val())
 
 		if len(keyBatch) == s.queryBatchSize {
-			if err := runQueries(keyBatch); err != nil {
-				return nil, fmt.Errorf("unable to run queries: %w", err)
+			if err := s.get(ctx, keyBatch, found); err != nil {
+				return err
 			}
 
 			keyBatch = nil
 		}
 	}
 
-	return res, nil
+	if err := iter.Err(); err != nil {
+		return fmt.Errorf("unable to scan: %w", err)
+	}
+
+	if len(keyBatch) > 0 {
+		return s.get(ctx, keyBatch, found)
+	}
+
+	return nil
+}
+
+// get calls f with the state of the device at each of keys. state is nil if
+// the key doesn't exist.
+func (s *StateStore) get(ctx context.Context, keys []string, f func(key string, state map[string]interface{})) error {
+	vals, err := s.rdb.MGet(ctx, keys...).Result()
+	if err != nil {
+		return fmt.Errorf("unable to mget: %w", err)
+	}
+
+	for i, key := range keys {
+		switch val := vals[i].(type) {
+		case nil:
+			f(key, nil)
+		case string:
+			var state map[string]interface{}
+			if err := json.Unmarshal([]byte(val), &state); err != nil {
+				s.Log.Warn("invalid device in redis", zap.String("key", key))
+				continue
+			}
+
+			f(key, state)
+		default:
+			s.Log.Warn("unexpected value in redis", zap.String("key", key), zap.String("type", fmt.Sprintf("%T", val)))
+		}
+	}
+
+	return nil
+}
+
+// runQueries runs each query against the device with state, returning the
+// device and the queries it matches at now
+func runQueries(log *zap.Logger, queries map[string]smee.DeviceStateQuery, state map[string]interface{}, now time.Time) smee.DeviceStateUpdate {
+	deviceID, _ := state["deviceID"].(string)
+	roomID, _ := state["room"].(string)
+
+	upd := smee.DeviceStateUpdate{
+		Device: smee.Device{
+			ID: deviceID,
+			Room: smee.Room{
+				ID: roomID,
+			},
+		},
+		Matches: make(map[string]bool),
+	}
+
+	for qName, q := range queries {
+		ok, err := q.Matches(state, now)
+		switch {
+		case err != nil:
+			log.Debug("unable to run query", zap.String("deviceID", deviceID), zap.String("query", qName), zap.Error(err))
+		case ok:
+			upd.Matches[qName] = true
+		}
+	}
+
+	return upd
 }
@@ -0,0 +1,110 @@
+package redis
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/byuoitav/smee/internal/smee"
+	"go.uber.org/zap"
+)
+
+// Simulator runs the device state queries against device state built from
+// events, instead of the state in redis, so that they can be run offline with
+// a fake clock. It approximates the state parser that fills redis: an event's
+// key sets the device field with the same name (as a number if its value is
+// one), every event counts as a state update of the device and of that field,
+// and heartbeat events set the last heartbeat.
+type Simulator struct {
+	devices map[string]map[string]interface{}
+}
+
+func NewSimulator() *Simulator {
+	return &Simulator{
+		devices: make(map[string]map[string]interface{}),
+	}
+}
+
+// Apply updates the state of event's device as of t
+func (s *Simulator) Apply(t time.Time, event smee.Event) {
+	if event.DeviceID == "" {
+		return
+	}
+
+	ts := t.Format(time.RFC3339Nano)
+
+	dev, ok := s.devices[event.DeviceID]
+	if !ok {
+		dev = map[string]interface{}{
+			"deviceID":    event.DeviceID,
+			"room":        event.RoomID,
+			"device-type": deviceType(event.DeviceID),
+
+			// devices that haven't sent a state update, heartbeat, or
+			// websocket count yet are treated as if they just did
+			"last-state-received": ts,
+			"last-heartbeat":      ts,
+			"websocket-count":     float64(1),
+			"field-state-received": map[string]interface{}{
+				"websocket-count": ts,
+			},
+		}
+		s.devices[event.DeviceID] = dev
+	}
+
+	dev["last-state-received"] = ts
+
+	if event.Key == "" {
+		return
+	}
+
+	if event.Key == "heartbeat" {
+		dev["last-heartbeat"] = ts
+	}
+
+	if f, err := strconv.ParseFloat(event.Value, 64); err == nil {
+		dev[event.Key] = f
+	} else {
+		dev[event.Key] = event.Value
+	}
+
+	dev["field-state-received"].(map[string]interface{})[event.Key] = ts
+}
+
+// RunAlertQueries returns a map of queryName -> devices that match the query at now
+func (s *Simulator) RunAlertQueries(now time.Time, queries map[string]smee.DeviceStateQuery) map[string][]smee.Device {
+	// every query has an entry, so that a query without any matches
+	// closes the alerts it opened
+	res := make(map[string][]smee.Device, len(queries))
+	for name := range queries {
+		res[name] = nil
+	}
+	for _, dev := range s.devices {
+		upd := runQueries(zap.NewNop(), queries, dev, now)
+		for qName := range upd.Matches {
+			res[qName] = append(res[qName], upd.Device)
+		}
+	}
+
+	return res
+}
+
+// deviceType guesses a device's type from its ID, for devices whose
+// type isn't in the events
+func deviceType(id string) string {
+	i := strings.LastIndex(id, "-")
+	prefix := strings.TrimRight(id[i+1:], "0123456789")
+
+	switch prefix {
+	case "D":
+		return "display"
+	case "CP", "DMPS":
+		return "control-processor"
+	case "SP":
+		return "scheduling-panel"
+	case "MIC":
+		return "microphone"
+	}
+
+	return ""
+}
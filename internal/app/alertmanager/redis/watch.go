@@ -0,0 +1,221 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/byuoitav/smee/internal/smee"
+	"go.uber.org/zap"
+)
+
+const (
+	// wheelTick is how often changed devices and due timers are run
+	wheelTick = time.Second
+
+	// wheelSize is how many ticks the timer wheel covers in one turn
+	wheelSize = 1024
+)
+
+// watch is the state of a running WatchAlertQueries
+type watch struct {
+	log     *zap.Logger
+	queries func() map[string]smee.DeviceStateQuery
+	update  func(smee.DeviceStateUpdate)
+	timers  *timerWheel
+
+	// devices is a map of key -> the last result of running the queries
+	// against the device at that key
+	devices map[string]smee.DeviceStateUpdate
+}
+
+// WatchAlertQueries runs the queries against devices as their state changes,
+// using redis keyspace notifications. Queries whose result changes as time
+// passes (like since(last-heartbeat) > 6m) are run again when they may have
+// changed, using a timer wheel. Changes are batched and run every second,
+// and update is only called when the queries a device matches change.
+// Changes missed while the subscription is reconnecting aren't run, so
+// RunAlertQueries should still be run periodically to catch them.
+func (s *StateStore) WatchAlertQueries(ctx context.Context, queries func() map[string]smee.DeviceStateQuery, update func(smee.DeviceStateUpdate)) error {
+	s.enableNotifications(ctx)
+
+	prefix := fmt.Sprintf("__keyspace@%d__:", s.rdb.Options().DB)
+
+	pubsub := s.rdb.PSubscribe(ctx, prefix+"*")
+	defer pubsub.Close()
+
+	// wait for the subscription to be confirmed, so that changes made while
+	// the timers are seeded aren't missed
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return fmt.Errorf("unable to subscribe to keyspace notifications: %w", err)
+	}
+
+	w := &watch{
+		log:     s.Log,
+		queries: queries,
+		update:  update,
+		timers:  newTimerWheel(wheelTick, wheelSize, time.Now()),
+		devices: make(map[string]smee.DeviceStateUpdate),
+	}
+
+	// run every device once to seed the timers
+	now := time.Now()
+	if err := s.scan(ctx, func(key string, state map[string]interface{}) {
+		w.run(key, state, now)
+	}); err != nil {
+		return fmt.Errorf("unable to seed timers: %w", err)
+	}
+
+	s.Log.Info("Watching device state", zap.Int("devices", len(w.devices)), zap.Int("timers", w.timers.len()))
+
+	ticker := time.NewTicker(wheelTick)
+	defer ticker.Stop()
+
+	msgs := pubsub.ChannelSize(1024)
+	changed := make(map[string]bool)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg, ok := <-msgs:
+			if !ok {
+				return errors.New("keyspace notifications closed")
+			}
+
+			keyspaceNotifications.Inc()
+			changed[strings.TrimPrefix(msg.Channel, prefix)] = true
+		case now := <-ticker.C:
+			keys := w.timers.advance(now)
+			for key := range changed {
+				keys = append(keys, key)
+			}
+
+			changed = make(map[string]bool)
+			s.runKeys(ctx, w, keys, now)
+			watchedTimers.Set(float64(w.timers.len()))
+		}
+	}
+}
+
+// runKeys runs the queries against the devices at keys, in batches
+func (s *StateStore) runKeys(ctx context.Context, w *watch, keys []string, now time.Time) {
+	for len(keys) > 0 {
+		n := s.queryBatchSize
+		if n > len(keys) {
+			n = len(keys)
+		}
+
+		err := s.get(ctx, keys[:n], func(key string, state map[string]interface{}) {
+			w.run(key, state, now)
+		})
+		if err != nil {
+			// the next sweep will pick these devices up
+			s.Log.Warn("unable to get changed devices", zap.Error(err), zap.Int("count", n))
+		}
+
+		keys = keys[n:]
+	}
+}
+
+// run runs the queries against the device at key, and schedules it to be
+// run again when a query's result on it may change. update is only called
+// if the set of queries the device matches changed. state is nil if key was
+// deleted.
+func (w *watch) run(key string, state map[string]interface{}, now time.Time) {
+	if state == nil {
+		w.timers.remove(key)
+
+		last, ok := w.devices[key]
+		if !ok {
+			return
+		}
+
+		delete(w.devices, key)
+		if len(last.Matches) > 0 {
+			w.update(smee.DeviceStateUpdate{Device: last.Device, Matches: map[string]bool{}})
+		}
+
+		return
+	}
+
+	queries := w.queries()
+
+	upd := runQueries(w.log, queries, state, now)
+	if last, ok := w.devices[key]; !ok || !sameMatches(last, upd) {
+		w.devices[key] = upd
+		w.update(upd)
+	}
+
+	var next time.Time
+	for _, q := range queries {
+		timed, ok := q.(smee.TimedDeviceStateQuery)
+		if !ok {
+			continue
+		}
+
+		if t, ok := timed.NextChange(state, now); ok && (next.IsZero() || t.Before(next)) {
+			next = t
+		}
+	}
+
+	if next.IsZero() {
+		w.timers.remove(key)
+		return
+	}
+
+	w.timers.schedule(key, next)
+}
+
+func sameMatches(a, b smee.DeviceStateUpdate) bool {
+	if a.Device != b.Device || len(a.Matches) != len(b.Matches) {
+		return false
+	}
+
+	for qName := range a.Matches {
+		if !b.Matches[qName] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// enableNotifications turns on the keyspace notifications for string
+// commands, deletes, and expirations, if they aren't already on. Redis
+// services that don't allow CONFIG need them turned on by hand.
+func (s *StateStore) enableNotifications(ctx context.Context) {
+	res, err := s.rdb.ConfigGet(ctx, "notify-keyspace-events").Result()
+	if err != nil || len(res) != 2 {
+		s.Log.Warn("unable to get keyspace notification config, make sure notify-keyspace-events includes K$gx", zap.Error(err))
+		return
+	}
+
+	flags, _ := res[1].(string)
+
+	want := flags
+	if !strings.Contains(want, "K") {
+		want += "K"
+	}
+
+	if !strings.Contains(want, "A") {
+		for _, c := range []string{"$", "g", "x"} {
+			if !strings.Contains(want, c) {
+				want += c
+			}
+		}
+	}
+
+	if want == flags {
+		return
+	}
+
+	if err := s.rdb.ConfigSet(ctx, "notify-keyspace-events", want).Err(); err != nil {
+		s.Log.Warn("unable to enable keyspace notifications, make sure notify-keyspace-events includes K$gx", zap.Error(err), zap.String("current", flags))
+		return
+	}
+
+	s.Log.Info("Enabled keyspace notifications", zap.String("notify-keyspace-events", want))
+}
@@ -0,0 +1,92 @@
+package redis
+
+import "time"
+
+// timerWheel schedules device keys to have their queries run again at a
+// given time. It is a hashed timing wheel: a ring of slots that are each one
+// tick long, where a key is put in the slot its time falls in. Keys more than
+// a full turn away stay in their slot until the turn they are due.
+type timerWheel struct {
+	tick  time.Duration
+	slots []map[string]time.Time
+
+	// slot is a map of key -> the slot it is in
+	slot map[string]int
+
+	// cur is the slot that ends at at
+	cur int
+	at  time.Time
+}
+
+func newTimerWheel(tick time.Duration, size int, now time.Time) *timerWheel {
+	w := &timerWheel{
+		tick:  tick,
+		slots: make([]map[string]time.Time, size),
+		slot:  make(map[string]int),
+		at:    now,
+	}
+
+	for i := range w.slots {
+		w.slots[i] = make(map[string]time.Time)
+	}
+
+	return w
+}
+
+// schedule schedules key for t, replacing the time it was scheduled for
+func (w *timerWheel) schedule(key string, t time.Time) {
+	w.remove(key)
+
+	ticks := int((t.Sub(w.at) + w.tick - 1) / w.tick)
+	if ticks < 1 {
+		ticks = 1
+	}
+
+	i := (w.cur + ticks) % len(w.slots)
+	w.slots[i][key] = t
+	w.slot[key] = i
+}
+
+// remove unschedules key
+func (w *timerWheel) remove(key string) {
+	if i, ok := w.slot[key]; ok {
+		delete(w.slots[i], key)
+		delete(w.slot, key)
+	}
+}
+
+// advance moves the wheel forward to now, returning the keys that are due
+func (w *timerWheel) advance(now time.Time) []string {
+	var due []string
+
+	for i := 0; i < len(w.slots) && !w.at.Add(w.tick).After(now); i++ {
+		w.at = w.at.Add(w.tick)
+		w.cur = (w.cur + 1) % len(w.slots)
+
+		for key, t := range w.slots[w.cur] {
+			if t.After(now) {
+				// due on a later turn
+				continue
+			}
+
+			due = append(due, key)
+			delete(w.slots[w.cur], key)
+			delete(w.slot, key)
+		}
+	}
+
+	// if the wheel was more than a full turn behind, every slot has been
+	// checked, so just catch it up. cur has to move with at so that keys
+	// stay in the slot for their time.
+	if behind := int(now.Sub(w.at) / w.tick); behind > 0 {
+		w.at = w.at.Add(time.Duration(behind) * w.tick)
+		w.cur = (w.cur + behind) % len(w.slots)
+	}
+
+	return due
+}
+
+// len returns the number of scheduled keys
+func (w *timerWheel) len() int {
+	return len(w.slot)
+}
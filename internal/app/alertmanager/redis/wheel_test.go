@@ -0,0 +1,43 @@
+package redis
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+)
+
+func TestTimerWheel(t *testing.T) {
+	is := is.New(t)
+
+	start := time.Date(2021, 3, 4, 12, 0, 0, 0, time.UTC)
+	w := newTimerWheel(time.Second, 8, start)
+
+	w.schedule("a", start.Add(2500*time.Millisecond))
+	w.schedule("b", start.Add(20*time.Second)) // more than a turn away
+	w.schedule("c", start.Add(time.Second))
+	w.schedule("c", start.Add(5*time.Second)) // rescheduled
+	w.schedule("d", start.Add(4*time.Second))
+	w.remove("d")
+
+	is.Equal(w.len(), 3)
+	is.Equal(len(w.advance(start.Add(2*time.Second))), 0)
+	is.Equal(w.advance(start.Add(3*time.Second)), []string{"a"})
+	is.Equal(len(w.advance(start.Add(4*time.Second))), 0)
+	is.Equal(w.advance(start.Add(5*time.Second)), []string{"c"})
+	is.Equal(len(w.advance(start.Add(19*time.Second))), 0)
+	is.Equal(w.advance(start.Add(20*time.Second)), []string{"b"})
+	is.Equal(w.len(), 0)
+
+	// falling more than a turn behind fires everything that is due
+	now := start.Add(20 * time.Second)
+	w.schedule("e", now.Add(3*time.Second))
+	w.schedule("f", now.Add(30*time.Second))
+	w.schedule("g", now.Add(time.Hour))
+
+	due := w.advance(now.Add(time.Minute))
+	sort.Strings(due)
+	is.Equal(due, []string{"e", "f"})
+	is.Equal(w.len(), 1)
+}
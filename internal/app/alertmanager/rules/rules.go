@@ -0,0 +1,90 @@
+// Package rules decides when alerts open and close. The alert manager and
+// the offline rule tester (ruletest) both use it, so that a rule test makes
+// the same decisions the alert manager would have.
+package rules
+
+import (
+	"sort"
+	"time"
+
+	"github.com/byuoitav/smee/internal/smee"
+)
+
+// Key identifies the alert of a type on a device
+type Key struct {
+	RoomID   string
+	DeviceID string
+	Type     string
+}
+
+// KeyOf returns the key of alerts of typ on dev
+func KeyOf(dev smee.Device, typ string) Key {
+	return Key{RoomID: dev.Room.ID, DeviceID: dev.ID, Type: typ}
+}
+
+// ErrorFunc is called when an alert type's transition can't be evaluated
+// against an event, like when a numeric condition gets a value that isn't a
+// number. The transition doesn't match.
+type ErrorFunc func(typ string, event smee.Event, err error)
+
+// Creates returns the alert types in configs whose create transition
+// matches event, in order
+func Creates(configs map[string]smee.AlertConfig, event smee.Event, errf ErrorFunc) []string {
+	var types []string
+	for typ, config := range configs {
+		if matches(config.Create.Event, typ, event, errf) {
+			types = append(types, typ)
+		}
+	}
+
+	sort.Strings(types)
+	return types
+}
+
+// Closes returns true if event matches the close transition of typ
+func Closes(configs map[string]smee.AlertConfig, typ string, event smee.Event, errf ErrorFunc) bool {
+	config, ok := configs[typ]
+	if !ok {
+		return false
+	}
+
+	return matches(config.Close.Event, typ, event, errf)
+}
+
+// Expired returns true if an alert of config that started at start has been
+// open longer than config's TTL at now
+func Expired(config smee.AlertConfig, start, now time.Time) bool {
+	return config.TTL > 0 && now.Sub(start) >= config.TTL
+}
+
+func matches(trans *smee.AlertTransitionEvent, typ string, event smee.Event, errf ErrorFunc) bool {
+	if trans == nil {
+		return false
+	}
+
+	ok, err := trans.Matches(event)
+	if err != nil {
+		if errf != nil {
+			errf(typ, event, err)
+		}
+
+		return false
+	}
+
+	return ok
+}
+
+// SortKeys sorts keys by room, device, and type
+func SortKeys(keys []Key) {
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		switch {
+		case a.RoomID != b.RoomID:
+			return a.RoomID < b.RoomID
+		case a.DeviceID != b.DeviceID:
+			return a.DeviceID < b.DeviceID
+		default:
+			return a.Type < b.Type
+		}
+	})
+}
@@ -0,0 +1,58 @@
+package rules
+
+import (
+	"testing"
+	"time"
+
+	"github.com/byuoitav/smee/internal/smee"
+	"github.com/matryer/is"
+)
+
+func TestSchedule(t *testing.T) {
+	is := is.New(t)
+
+	now := time.Date(2021, 3, 1, 8, 0, 0, 0, time.UTC)
+	configs := map[string]smee.StateAlertConfig{
+		"sys-offline": {Interval: time.Minute},
+		"websocket":   {},
+	}
+
+	s := Schedule{Default: 30 * time.Second}
+
+	// nothing runs until an interval after the queries show up
+	is.Equal(len(s.Due(configs, now)), 0)
+
+	next, ok := s.Next(configs)
+	is.True(ok)
+	is.Equal(next, now.Add(30*time.Second))
+
+	due := s.Due(configs, next)
+	is.Equal(len(due), 1)
+	_, ok = due["websocket"]
+	is.True(ok)
+
+	due = s.Due(configs, now.Add(time.Minute))
+	is.Equal(len(due), 2)
+}
+
+func TestStateChanges(t *testing.T) {
+	is := is.New(t)
+
+	cp1 := smee.Device{ID: "ITB-1101-CP1", Room: smee.Room{ID: "ITB-1101"}}
+	cp2 := smee.Device{ID: "ITB-1102-CP1", Room: smee.Room{ID: "ITB-1102"}}
+
+	res := map[string][]smee.Device{
+		"sys-offline": {cp1},
+		"websocket":   nil,
+	}
+
+	open := []Key{
+		KeyOf(cp2, "sys-offline"),
+		KeyOf(cp1, "websocket"),
+		KeyOf(cp1, "lamp-hours"), // wasn't run
+	}
+
+	opened, closed := StateChanges(res, open)
+	is.Equal(opened, []Key{KeyOf(cp1, "sys-offline")})
+	is.Equal(closed, []Key{KeyOf(cp1, "websocket"), KeyOf(cp2, "sys-offline")})
+}
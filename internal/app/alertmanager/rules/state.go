@@ -0,0 +1,121 @@
+package rules
+
+import (
+	"time"
+
+	"github.com/byuoitav/smee/internal/smee"
+)
+
+// Schedule decides when each state query is run. A query is first run an
+// interval after it shows up in the configs, and then every interval after
+// its last run.
+type Schedule struct {
+	// Default is the interval of queries that don't set their own
+	Default time.Duration
+
+	// last is a map of state alert type -> when its query was last run
+	last map[string]time.Time
+}
+
+// Due returns the configs whose queries are due at now, and records that
+// they were run
+func (s *Schedule) Due(configs map[string]smee.StateAlertConfig, now time.Time) map[string]smee.StateAlertConfig {
+	if s.last == nil {
+		s.last = make(map[string]time.Time, len(configs))
+	}
+
+	due := make(map[string]smee.StateAlertConfig)
+	for typ, config := range configs {
+		last, ok := s.last[typ]
+		switch {
+		case !ok:
+			s.last[typ] = now
+		case now.Sub(last) >= s.interval(config):
+			s.last[typ] = now
+			due[typ] = config
+		}
+	}
+
+	return due
+}
+
+// Ran records that every query in configs was run at now
+func (s *Schedule) Ran(configs map[string]smee.StateAlertConfig, now time.Time) {
+	if s.last == nil {
+		s.last = make(map[string]time.Time, len(configs))
+	}
+
+	for typ := range configs {
+		s.last[typ] = now
+	}
+}
+
+// Next returns when the next query in configs is due. It returns false if
+// none of them have been scheduled by Due yet.
+func (s *Schedule) Next(configs map[string]smee.StateAlertConfig) (time.Time, bool) {
+	var next time.Time
+	for typ, config := range configs {
+		last, ok := s.last[typ]
+		if !ok {
+			continue
+		}
+
+		if at := last.Add(s.interval(config)); next.IsZero() || at.Before(next) {
+			next = at
+		}
+	}
+
+	return next, !next.IsZero()
+}
+
+func (s *Schedule) interval(config smee.StateAlertConfig) time.Duration {
+	if config.Interval > 0 {
+		return config.Interval
+	}
+
+	return s.Default
+}
+
+// Queries returns a map of state alert type -> the query that creates it
+func Queries(configs map[string]smee.StateAlertConfig) map[string]smee.DeviceStateQuery {
+	queries := make(map[string]smee.DeviceStateQuery, len(configs))
+	for typ, config := range configs {
+		if config.Query != nil {
+			queries[typ] = config.Query
+		}
+	}
+
+	return queries
+}
+
+// StateChanges returns the alerts a run of the state queries opens and
+// closes, in order. res is a map of state alert type -> the devices that
+// matched its query; it has an entry for every query that was run. open is
+// the open alerts, and open alerts of types that weren't run are left alone.
+func StateChanges(res map[string][]smee.Device, open []Key) (opened, closed []Key) {
+	matched := make(map[Key]bool)
+	for typ, devices := range res {
+		for _, dev := range devices {
+			matched[KeyOf(dev, typ)] = true
+		}
+	}
+
+	isOpen := make(map[Key]bool, len(open))
+	for _, key := range open {
+		isOpen[key] = true
+
+		if _, ok := res[key.Type]; ok && !matched[key] {
+			closed = append(closed, key)
+		}
+	}
+
+	for key := range matched {
+		if !isOpen[key] {
+			opened = append(opened, key)
+		}
+	}
+
+	SortKeys(opened)
+	SortKeys(closed)
+	return opened, closed
+}
@@ -0,0 +1,309 @@
+// Package ruletest runs recorded events through alert rules offline, using
+// the events' timestamps as the clock, and reports when alerts would have
+// opened and closed.
+package ruletest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/byuoitav/smee/internal/app/alertmanager/rules"
+	"github.com/byuoitav/smee/internal/pkg/eventlog"
+	"github.com/byuoitav/smee/internal/smee"
+)
+
+// StateSimulator runs the device state queries against state built from events
+type StateSimulator interface {
+	Apply(t time.Time, event smee.Event)
+	RunAlertQueries(now time.Time, queries map[string]smee.DeviceStateQuery) map[string][]smee.Device
+}
+
+const (
+	ActionOpen  = "open"
+	ActionClose = "close"
+)
+
+// Transition is an alert opening or closing
+type Transition struct {
+	Time     time.Time
+	Action   string
+	RoomID   string
+	DeviceID string
+	Type     string
+
+	// Reason is what caused the transition
+	Reason string
+}
+
+// Evaluator runs events through AlertConfigs and the state queries, using
+// the same rules as the alert manager. It covers create/close transitions,
+// for, ttl, and forScans; maintenance, silences, inhibitions, and flapping
+// depend on live state and aren't simulated.
+type Evaluator struct {
+	AlertConfigs      map[string]smee.AlertConfig
+	StateAlertConfigs map[string]smee.StateAlertConfig
+
+	// State is optional. If set, the state queries are run every StateInterval.
+	State StateSimulator
+
+	// StateInterval defaults to 30s, like the alert manager. It is how often
+	// the queries of state alerts without their own Interval are run.
+	StateInterval time.Duration
+
+	now         time.Time
+	schedule    rules.Schedule
+	open        map[rules.Key]time.Time
+	pending     map[rules.Key]time.Time
+	scans       map[rules.Key]int
+	transitions []Transition
+}
+
+// Run reads every record from r and returns the resulting transitions in
+// order. After the last event, the clock keeps running for tail so that
+// alerts that depend on time passing (like pending or offline alerts) get a
+// chance to fire.
+func (e *Evaluator) Run(r *eventlog.Reader, tail time.Duration) ([]Transition, error) {
+	e.open = make(map[rules.Key]time.Time)
+	e.pending = make(map[rules.Key]time.Time)
+	e.scans = make(map[rules.Key]int)
+	e.transitions = nil
+	e.now = time.Time{}
+
+	if e.StateInterval <= 0 {
+		e.StateInterval = 30 * time.Second
+	}
+
+	e.schedule = rules.Schedule{Default: e.StateInterval}
+
+	for {
+		rec, err := r.Read()
+		switch {
+		case errors.Is(err, io.EOF):
+			if !e.now.IsZero() {
+				e.advance(e.now.Add(tail))
+			}
+
+			return e.transitions, nil
+		case err != nil:
+			return nil, err
+		}
+
+		if e.now.IsZero() {
+			// the first run of each query is an interval after the first event
+			e.now = rec.Timestamp
+			e.schedule.Due(e.StateAlertConfigs, e.now)
+		}
+
+		if rec.Timestamp.Before(e.now) {
+			return nil, fmt.Errorf("event at %v is before the previous event (%v)", rec.Timestamp.Format(time.RFC3339), e.now.Format(time.RFC3339))
+		}
+
+		e.advance(rec.Timestamp)
+		e.handleEvent(rec.Event())
+	}
+}
+
+// Open returns the alerts that are still open, oldest first
+func (e *Evaluator) Open() []Transition {
+	var open []Transition
+	for key, start := range e.open {
+		open = append(open, Transition{
+			Time:     start,
+			Action:   ActionOpen,
+			RoomID:   key.RoomID,
+			DeviceID: key.DeviceID,
+			Type:     key.Type,
+		})
+	}
+
+	sortTransitions(open)
+	return open
+}
+
+// advance moves the clock forward to t, firing everything that comes due on the way
+func (e *Evaluator) advance(t time.Time) {
+	for {
+		next, fire := e.nextTimer()
+		if fire == nil || next.After(t) {
+			break
+		}
+
+		e.now = next
+		fire()
+	}
+
+	e.now = t
+}
+
+// nextTimer returns the earliest pending timer and the func that fires it
+func (e *Evaluator) nextTimer() (time.Time, func()) {
+	var next time.Time
+	var fire func()
+
+	consider := func(t time.Time, f func()) {
+		if fire == nil || t.Before(next) {
+			next, fire = t, f
+		}
+	}
+
+	if e.State != nil {
+		if next, ok := e.schedule.Next(e.StateAlertConfigs); ok {
+			consider(next, e.runStateQueries)
+		}
+	}
+
+	for _, key := range sortedKeys(e.pending) {
+		key := key
+		consider(e.pending[key], func() {
+			delete(e.pending, key)
+			e.openAlert(key, "pending for "+e.AlertConfigs[key.Type].For.String())
+		})
+	}
+
+	for _, key := range sortedKeys(e.open) {
+		key := key
+		config := e.AlertConfigs[key.Type]
+		if config.TTL <= 0 {
+			continue
+		}
+
+		start := e.open[key]
+		consider(start.Add(config.TTL), func() {
+			if rules.Expired(config, start, e.now) {
+				e.closeAlert(key, "ttl "+config.TTL.String())
+			}
+		})
+	}
+
+	return next, fire
+}
+
+func (e *Evaluator) handleEvent(event smee.Event) {
+	if e.State != nil {
+		e.State.Apply(e.now, event)
+	}
+
+	// close first, so that an event can't close the alert it opens
+	for _, typ := range sortedTypes(e.AlertConfigs) {
+		key := rules.Key{RoomID: event.RoomID, DeviceID: event.DeviceID, Type: typ}
+
+		if !rules.Closes(e.AlertConfigs, typ, event, nil) {
+			continue
+		}
+
+		delete(e.pending, key)
+		if _, ok := e.open[key]; ok {
+			e.closeAlert(key, "value: "+event.Value)
+		}
+	}
+
+	for _, typ := range rules.Creates(e.AlertConfigs, event, nil) {
+		config := e.AlertConfigs[typ]
+		key := rules.Key{RoomID: event.RoomID, DeviceID: event.DeviceID, Type: typ}
+
+		if _, ok := e.open[key]; ok {
+			continue
+		}
+
+		if config.For > 0 {
+			if _, ok := e.pending[key]; !ok {
+				e.pending[key] = e.now.Add(config.For)
+			}
+
+			continue
+		}
+
+		e.openAlert(key, "value: "+event.Value)
+	}
+}
+
+func (e *Evaluator) runStateQueries() {
+	due := e.schedule.Due(e.StateAlertConfigs, e.now)
+	res := e.State.RunAlertQueries(e.now, rules.Queries(due))
+
+	opened, closed := rules.StateChanges(res, sortedKeys(e.open))
+
+	matched := make(map[rules.Key]bool, len(opened))
+	for _, key := range opened {
+		matched[key] = true
+
+		e.scans[key]++
+		if e.scans[key] < e.StateAlertConfigs[key.Type].ForScans {
+			continue
+		}
+
+		delete(e.scans, key)
+		e.openAlert(key, "state query")
+	}
+
+	for _, key := range closed {
+		e.closeAlert(key, "state query")
+	}
+
+	for key := range e.scans {
+		if _, ok := due[key.Type]; ok && !matched[key] {
+			delete(e.scans, key)
+		}
+	}
+}
+
+func (e *Evaluator) openAlert(key rules.Key, reason string) {
+	e.open[key] = e.now
+	e.record(ActionOpen, key, reason)
+}
+
+func (e *Evaluator) closeAlert(key rules.Key, reason string) {
+	delete(e.open, key)
+	e.record(ActionClose, key, reason)
+}
+
+func (e *Evaluator) record(action string, key rules.Key, reason string) {
+	e.transitions = append(e.transitions, Transition{
+		Time:     e.now,
+		Action:   action,
+		RoomID:   key.RoomID,
+		DeviceID: key.DeviceID,
+		Type:     key.Type,
+		Reason:   reason,
+	})
+}
+
+func sortedTypes(configs map[string]smee.AlertConfig) []string {
+	var types []string
+	for typ := range configs {
+		types = append(types, typ)
+	}
+
+	sort.Strings(types)
+	return types
+}
+
+// sortedKeys returns the keys of m in a stable order, so that runs are reproducible
+func sortedKeys(m map[rules.Key]time.Time) []rules.Key {
+	keys := make([]rules.Key, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	rules.SortKeys(keys)
+	return keys
+}
+
+func sortTransitions(transitions []Transition) {
+	sort.SliceStable(transitions, func(i, j int) bool {
+		a, b := transitions[i], transitions[j]
+		switch {
+		case !a.Time.Equal(b.Time):
+			return a.Time.Before(b.Time)
+		case a.RoomID != b.RoomID:
+			return a.RoomID < b.RoomID
+		case a.DeviceID != b.DeviceID:
+			return a.DeviceID < b.DeviceID
+		default:
+			return a.Type < b.Type
+		}
+	})
+}
@@ -0,0 +1,51 @@
+package ruletest
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/byuoitav/smee/internal/app/alertmanager/config"
+	"github.com/byuoitav/smee/internal/app/alertmanager/redis"
+	"github.com/byuoitav/smee/internal/pkg/eventlog"
+	"github.com/matryer/is"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+func TestTimelineGolden(t *testing.T) {
+	is := is.New(t)
+
+	cfg, err := config.Load("testdata/alerts.yaml")
+	is.NoErr(err)
+
+	events, err := os.Open("testdata/events.jsonl")
+	is.NoErr(err)
+	defer events.Close()
+
+	eval := &Evaluator{
+		AlertConfigs:      cfg.AlertConfigs(),
+		StateAlertConfigs: cfg.StateAlertConfigs(),
+		State:             redis.NewSimulator(),
+	}
+
+	transitions, err := eval.Run(eventlog.NewReader(events), 10*time.Minute)
+	is.NoErr(err)
+
+	var got bytes.Buffer
+	is.NoErr(WriteTimeline(&got, transitions, eval.Open()))
+
+	if *update {
+		is.NoErr(ioutil.WriteFile("testdata/timeline.golden", got.Bytes(), 0644))
+	}
+
+	want, err := ioutil.ReadFile("testdata/timeline.golden")
+	is.NoErr(err)
+
+	if diff := Diff(want, got.Bytes()); diff != "" {
+		t.Fatalf("timeline does not match golden file (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,115 @@
+package ruletest
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// WriteTimeline writes transitions grouped by device, followed by the alerts
+// that were still open at the end. Times are written in UTC so that the
+// output doesn't depend on where it is run.
+func WriteTimeline(w io.Writer, transitions, open []Transition) error {
+	type device struct {
+		roomID, deviceID string
+	}
+
+	byDevice := make(map[device][]Transition)
+	var devices []device
+
+	for _, t := range transitions {
+		dev := device{roomID: t.RoomID, deviceID: t.DeviceID}
+		if _, ok := byDevice[dev]; !ok {
+			devices = append(devices, dev)
+		}
+
+		byDevice[dev] = append(byDevice[dev], t)
+	}
+
+	sort.Slice(devices, func(i, j int) bool {
+		if devices[i].roomID != devices[j].roomID {
+			return devices[i].roomID < devices[j].roomID
+		}
+
+		return devices[i].deviceID < devices[j].deviceID
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	for i, dev := range devices {
+		if i > 0 {
+			fmt.Fprintln(tw)
+		}
+
+		fmt.Fprintf(tw, "%s %s\n", dev.roomID, dev.deviceID)
+		for _, t := range byDevice[dev] {
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\n", t.Time.UTC().Format(time.RFC3339), t.Action, t.Type, t.Reason)
+		}
+	}
+
+	if len(devices) == 0 {
+		fmt.Fprintln(tw, "no alerts opened or closed")
+	}
+
+	if len(open) > 0 {
+		fmt.Fprintf(tw, "\nstill open:\n")
+		for _, t := range open {
+			fmt.Fprintf(tw, "  %s\t%s %s\t%s\n", t.Time.UTC().Format(time.RFC3339), t.RoomID, t.DeviceID, t.Type)
+		}
+	}
+
+	return tw.Flush()
+}
+
+// Diff returns a line diff from want to got, or "" if they are the same.
+// Removed lines are prefixed with "-" and added lines with "+".
+func Diff(want, got []byte) string {
+	if bytes.Equal(want, got) {
+		return ""
+	}
+
+	a := strings.Split(string(want), "\n")
+	b := strings.Split(string(got), "\n")
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var sb strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			sb.WriteString("  " + a[i] + "\n")
+			i++
+			j++
+		case j < len(b) && (i == len(a) || lcs[i][j+1] >= lcs[i+1][j]):
+			sb.WriteString("+ " + b[j] + "\n")
+			j++
+		default:
+			sb.WriteString("- " + a[i] + "\n")
+			i++
+		}
+	}
+
+	return sb.String()
+}
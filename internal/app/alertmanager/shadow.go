@@ -0,0 +1,91 @@
+package alertmanager
+
+import (
+	"context"
+	"time"
+
+	"github.com/byuoitav/smee/internal/smee"
+	"go.uber.org/zap"
+)
+
+// isShadow returns true if alerts of typ should only be written to the ShadowStore
+func (m *Manager) isShadow(typ string) bool {
+	if config, ok := m.alertConfigs()[typ]; ok && config.Shadow {
+		return true
+	}
+
+	config, ok := m.stateAlertConfigs()[typ]
+	return ok && config.Shadow
+}
+
+// activeAlertsByType returns the active alerts of typ, from the ShadowStore
+// if typ is in shadow mode
+func (m *Manager) activeAlertsByType(ctx context.Context, typ string) ([]smee.Alert, error) {
+	if !m.isShadow(typ) {
+		return m.IssueStore.ActiveAlertsByType(ctx, typ)
+	}
+
+	return m.shadowActive.byType(typ), nil
+}
+
+// createShadowAlert records that action's alert would have been created. It
+// never touches issues, so notifications, incidents, and escalations aren't
+// triggered by shadow alerts.
+func (m *Manager) createShadowAlert(ctx context.Context, action alertAction) {
+	if m.ShadowStore == nil {
+		m.Log.Debug("Dropping shadow alert, no shadow store configured", zap.String("type", action.alert.Type))
+		return
+	}
+
+	alert := action.alert
+	for _, a := range m.shadowActive.device(alert.Device.Room.ID, alert.Device.ID) {
+		if a.Type == alert.Type {
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	alert, err := m.ShadowStore.OpenShadowAlert(ctx, alert, action.events...)
+	if err != nil {
+		m.Log.Error("unable to open shadow alert", zap.Error(err), zap.String("roomID", alert.Device.Room.ID), zap.String("deviceID", alert.Device.ID), zap.String("type", alert.Type))
+		return
+	}
+
+	m.Log.Info("Shadow alert would have fired", zap.String("roomID", alert.Device.Room.ID), zap.String("deviceID", alert.Device.ID), zap.String("type", alert.Type))
+	m.shadowActive.add(alert)
+}
+
+// closeShadowAlert records that action's shadow alert would have been closed
+func (m *Manager) closeShadowAlert(ctx context.Context, action alertAction) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	alert, err := m.ShadowStore.CloseShadowAlert(ctx, action.alert.ID, action.events...)
+	if err != nil {
+		m.Log.Error("unable to close shadow alert", zap.Error(err), zap.String("alertID", action.alert.ID))
+		return
+	}
+
+	m.Log.Info("Shadow alert would have closed", zap.String("roomID", alert.Device.Room.ID), zap.String("deviceID", alert.Device.ID), zap.String("type", alert.Type))
+	m.shadowActive.remove(action.alert)
+}
+
+// syncShadowAlerts rebuilds the shadow alert index from the shadow store
+func (m *Manager) syncShadowAlerts(ctx context.Context) {
+	if m.ShadowStore == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	alerts, err := m.ShadowStore.ActiveShadowAlerts(ctx)
+	if err != nil {
+		m.Log.Error("unable to sync shadow alerts", zap.Error(err))
+		return
+	}
+
+	m.shadowActive.reset(alerts)
+}
@@ -0,0 +1,117 @@
+// Package shadow is an in-memory smee.ShadowStore. Shadow alerts are only
+// used to evaluate new alert types, so they aren't kept across restarts.
+package shadow
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/byuoitav/smee/internal/smee"
+)
+
+const defaultSize = 10000
+
+type Store struct {
+	// Size is the number of timeline entries to keep. Defaults to 10000.
+	Size int
+
+	mu       sync.Mutex
+	active   map[string]smee.Alert
+	timeline []smee.ShadowEvent
+	lastID   int
+}
+
+func (s *Store) OpenShadowAlert(ctx context.Context, alert smee.Alert, events ...smee.IssueEvent) (smee.Alert, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.active == nil {
+		s.active = make(map[string]smee.Alert)
+	}
+
+	// prefixed so that shadow alerts can't be confused with real ones
+	s.lastID++
+	alert.ID = fmt.Sprintf("shadow-%d", s.lastID)
+	alert.IssueID = ""
+	if alert.Start.IsZero() {
+		alert.Start = time.Now()
+	}
+
+	s.active[alert.ID] = alert
+	s.record(smee.ShadowEvent{
+		Timestamp: alert.Start,
+		Action:    smee.ShadowWouldHaveFired,
+		Alert:     alert,
+		Events:    events,
+	})
+
+	return alert, nil
+}
+
+func (s *Store) CloseShadowAlert(ctx context.Context, alertID string, events ...smee.IssueEvent) (smee.Alert, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	alert, ok := s.active[alertID]
+	if !ok {
+		return smee.Alert{}, fmt.Errorf("shadow alert %q is not active", alertID)
+	}
+
+	alert.End = time.Now()
+	delete(s.active, alertID)
+
+	s.record(smee.ShadowEvent{
+		Timestamp: alert.End,
+		Action:    smee.ShadowWouldHaveClosed,
+		Alert:     alert,
+		Events:    events,
+	})
+
+	return alert, nil
+}
+
+func (s *Store) ActiveShadowAlerts(ctx context.Context) ([]smee.Alert, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	alerts := make([]smee.Alert, 0, len(s.active))
+	for _, alert := range s.active {
+		alerts = append(alerts, alert)
+	}
+
+	sort.Slice(alerts, func(i, j int) bool {
+		return alerts[i].Start.Before(alerts[j].Start)
+	})
+
+	return alerts, nil
+}
+
+func (s *Store) ShadowTimeline(ctx context.Context, typ string) ([]smee.ShadowEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	timeline := []smee.ShadowEvent{}
+	for _, event := range s.timeline {
+		if typ == "" || event.Alert.Type == typ {
+			timeline = append(timeline, event)
+		}
+	}
+
+	return timeline, nil
+}
+
+// record appends event to the timeline, dropping the oldest entries once it is full
+func (s *Store) record(event smee.ShadowEvent) {
+	size := s.Size
+	if size <= 0 {
+		size = defaultSize
+	}
+
+	s.timeline = append(s.timeline, event)
+	if over := len(s.timeline) - size; over > 0 {
+		s.timeline = append(s.timeline[:0], s.timeline[over:]...)
+	}
+}
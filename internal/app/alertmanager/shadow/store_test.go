@@ -0,0 +1,42 @@
+package shadow
+
+import (
+	"context"
+	"testing"
+
+	"github.com/byuoitav/smee/internal/smee"
+	"github.com/matryer/is"
+)
+
+func TestStore(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	s := &Store{Size: 2}
+
+	alert, err := s.OpenShadowAlert(ctx, smee.Alert{Type: "new-query"})
+	is.NoErr(err)
+	is.True(alert.ID != "")
+
+	_, err = s.OpenShadowAlert(ctx, smee.Alert{Type: "other"})
+	is.NoErr(err)
+
+	_, err = s.CloseShadowAlert(ctx, alert.ID)
+	is.NoErr(err)
+
+	_, err = s.CloseShadowAlert(ctx, alert.ID)
+	is.True(err != nil) // already closed
+
+	active, err := s.ActiveShadowAlerts(ctx)
+	is.NoErr(err)
+	is.Equal(len(active), 1)
+
+	// the first entry was dropped to keep the timeline at Size
+	timeline, err := s.ShadowTimeline(ctx, "")
+	is.NoErr(err)
+	is.Equal(len(timeline), 2)
+
+	timeline, err = s.ShadowTimeline(ctx, "new-query")
+	is.NoErr(err)
+	is.Equal(len(timeline), 1)
+	is.Equal(timeline[0].Action, smee.ShadowWouldHaveClosed)
+}
@@ -0,0 +1,56 @@
+package alertmanager
+
+import (
+	"context"
+	"time"
+
+	"github.com/byuoitav/smee/internal/smee"
+	"go.uber.org/zap"
+)
+
+// silencedBy returns the active silence that matches alert, and false if
+// alert isn't silenced. If the silences can't be found, the alert is
+// treated as not silenced so that it isn't lost.
+func (m *Manager) silencedBy(ctx context.Context, alert smee.Alert) (smee.Silence, bool) {
+	if m.SilenceStore == nil {
+		return smee.Silence{}, false
+	}
+
+	silences, err := m.SilenceStore.Silences(ctx)
+	if err != nil {
+		m.Log.Warn("unable to get silences", zap.Error(err))
+		return smee.Silence{}, false
+	}
+
+	now := time.Now()
+	for _, s := range silences {
+		if s.Active(now) && s.Matches(alert) {
+			return s, true
+		}
+	}
+
+	return smee.Silence{}, false
+}
+
+// manageSilences creates silenced alerts that are still active once their
+// silence has expired or been deleted.
+func (m *Manager) manageSilences(ctx context.Context) error {
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			released := m.releaseHeldAlerts(ctx, heldForSilence, func(ctx context.Context, held heldAlert) bool {
+				_, ok := m.silencedBy(ctx, held.action.alert)
+				return ok
+			})
+
+			if released > 0 {
+				m.reevaluateState()
+			}
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package silence
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/byuoitav/smee/internal/smee"
+	"go.uber.org/zap"
+)
+
+type Cache struct {
+	SilenceStore smee.SilenceStore
+	Log          *zap.Logger
+
+	silences   map[string]smee.Silence
+	silencesMu sync.RWMutex
+
+	// lastID is used to create IDs when there isn't a SilenceStore
+	lastID int
+}
+
+func (c *Cache) Sync(ctx context.Context) error {
+	c.silencesMu.Lock()
+	defer c.silencesMu.Unlock()
+
+	c.silences = make(map[string]smee.Silence)
+
+	if c.SilenceStore != nil {
+		silences, err := c.SilenceStore.Silences(ctx)
+		if err != nil {
+			return fmt.Errorf("unable to get silences: %w", err)
+		}
+
+		for _, s := range silences {
+			c.silences[s.ID] = s
+		}
+	}
+
+	c.Log.Info("Synced cache", zap.Int("silences", len(c.silences)))
+	return nil
+}
+
+func (c *Cache) Silences(ctx context.Context) ([]smee.Silence, error) {
+	c.silencesMu.Lock()
+	defer c.silencesMu.Unlock()
+
+	now := time.Now()
+	silences := make([]smee.Silence, 0, len(c.silences))
+
+	for id, s := range c.silences {
+		if !now.Before(s.End) {
+			delete(c.silences, id)
+			continue
+		}
+
+		silences = append(silences, s)
+	}
+
+	sort.Slice(silences, func(i, j int) bool {
+		return silences[i].Start.Before(silences[j].Start)
+	})
+
+	return silences, nil
+}
+
+func (c *Cache) Silence(ctx context.Context, id string) (smee.Silence, error) {
+	c.silencesMu.RLock()
+	s, ok := c.silences[id]
+	c.silencesMu.RUnlock()
+
+	if ok {
+		return s, nil
+	}
+
+	// expired silences are only in the substore
+	if c.SilenceStore != nil {
+		return c.SilenceStore.Silence(ctx, id)
+	}
+
+	return smee.Silence{}, smee.ErrSilenceNotFound
+}
+
+func (c *Cache) CreateSilence(ctx context.Context, s smee.Silence) (smee.Silence, error) {
+	c.silencesMu.Lock()
+	defer c.silencesMu.Unlock()
+
+	if c.SilenceStore != nil {
+		var err error
+		s, err = c.SilenceStore.CreateSilence(ctx, s)
+		if err != nil {
+			return smee.Silence{}, fmt.Errorf("unable to create silence on substore: %w", err)
+		}
+	} else {
+		c.lastID++
+		s.ID = strconv.Itoa(c.lastID)
+	}
+
+	c.silences[s.ID] = s
+	return s, nil
+}
+
+func (c *Cache) UpdateSilence(ctx context.Context, s smee.Silence) (smee.Silence, error) {
+	c.silencesMu.Lock()
+	defer c.silencesMu.Unlock()
+
+	if c.SilenceStore != nil {
+		var err error
+		s, err = c.SilenceStore.UpdateSilence(ctx, s)
+		if err != nil {
+			return smee.Silence{}, fmt.Errorf("unable to update silence on substore: %w", err)
+		}
+	} else if _, ok := c.silences[s.ID]; !ok {
+		return smee.Silence{}, smee.ErrSilenceNotFound
+	}
+
+	c.silences[s.ID] = s
+	return s, nil
+}
+
+func (c *Cache) DeleteSilence(ctx context.Context, id string) error {
+	c.silencesMu.Lock()
+	defer c.silencesMu.Unlock()
+
+	if c.SilenceStore != nil {
+		if err := c.SilenceStore.DeleteSilence(ctx, id); err != nil {
+			return fmt.Errorf("unable to delete silence on substore: %w", err)
+		}
+	} else if _, ok := c.silences[id]; !ok {
+		return smee.ErrSilenceNotFound
+	}
+
+	delete(c.silences, id)
+	return nil
+}
@@ -5,102 +5,207 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/byuoitav/smee/internal/app/alertmanager/rules"
 	"github.com/byuoitav/smee/internal/smee"
+	"go.uber.org/zap"
 )
 
+const (
+	// stateQueryInterval is how often the device state queries are run, for
+	// queries that don't set their own interval
+	stateQueryInterval = 30 * time.Second
+
+	// defaultStateSweepInterval is the default if the DeviceStateStore
+	// reports changes as they happen
+	defaultStateSweepInterval = 5 * time.Minute
+
+	// stateScheduleTick is how often manageStateAlerts checks for queries
+	// that are due
+	stateScheduleTick = time.Second
+)
+
+// manageStateAlerts runs each device state query against every device on
+// its own interval. If the DeviceStateStore is a smee.DeviceStateWatcher,
+// the queries are also run as state changes by watchStateAlerts, so queries
+// without an interval are only run every StateSweepInterval to catch changes
+// the watch missed.
 func (m *Manager) manageStateAlerts(ctx context.Context) error {
-	ticker := time.NewTicker(30 * time.Second)
+	def := stateQueryInterval
+	if _, ok := m.DeviceStateStore.(smee.DeviceStateWatcher); ok {
+		def = m.StateSweepInterval
+		if def <= 0 {
+			def = defaultStateSweepInterval
+		}
+	}
+
+	ticker := time.NewTicker(stateScheduleTick)
 	defer ticker.Stop()
 
-	// strip out the device/room name because those aren't always available
-	key := func(dev smee.Device) smee.Device {
-		return smee.Device{
-			ID: dev.ID,
-			Room: smee.Room{
-				ID: dev.Room.ID,
-			},
+	schedule := rules.Schedule{Default: def}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-m.reevaluate:
+			configs := m.stateAlertConfigs()
+			schedule.Ran(configs, time.Now())
+
+			m.runStateQueries(ctx, configs)
+		case now := <-ticker.C:
+			if due := schedule.Due(m.stateAlertConfigs(), now); len(due) > 0 {
+				m.runStateQueries(ctx, due)
+			}
 		}
 	}
+}
+
+// runStateQueries creates/closes the alerts of the types in configs based on
+// the current device state
+func (m *Manager) runStateQueries(ctx context.Context, configs map[string]smee.StateAlertConfig) {
+	// figure out which devices should be alerting
+	res, err := m.DeviceStateStore.RunAlertQueries(ctx, rules.Queries(configs))
+	if err != nil {
+		m.Log.Warn("unable to run state queries", zap.Error(err))
+		return
+	}
+
+	// the currently open alerts of the types that were run
+	active := make(map[rules.Key]smee.Alert)
+	var open []rules.Key
+	for typ := range res {
+		alerts, err := m.activeAlertsByType(ctx, typ)
+		if err != nil {
+			m.Log.Warn("unable to get active alerts", zap.Error(err), zap.String("type", typ))
+			delete(res, typ)
+			continue
+		}
+
+		for _, alert := range alerts {
+			key := rules.KeyOf(alert.Device, typ)
+			active[key] = alert
+			open = append(open, key)
+		}
+	}
+
+	opened, closed := rules.StateChanges(res, open)
+
+	// the pending state alerts that are still matching
+	seen := make(map[alertKey]bool)
+	defer m.resetPendingStateAlerts(configs, seen)
+
+	for _, key := range opened {
+		action := createStateAlert(smee.Device{ID: key.DeviceID, Room: smee.Room{ID: key.RoomID}}, key.Type)
+
+		seen[keyOf(action.alert)] = true
+		if !m.pendingStateAlert(action, configs[key.Type].ForScans) {
+			continue
+		}
+
+		m.enqueue(ctx, action)
+	}
+
+	for _, key := range closed {
+		m.enqueue(ctx, closeStateAlert(active[key]))
+	}
+}
+
+// watchStateAlerts creates/closes state alerts as device state changes
+func (m *Manager) watchStateAlerts(ctx context.Context, watcher smee.DeviceStateWatcher) error {
+	backoff := streamMinBackoff
+
+	queries := func() map[string]smee.DeviceStateQuery {
+		return rules.Queries(m.stateAlertConfigs())
+	}
 
 	for {
+		err := watcher.WatchAlertQueries(ctx, queries, func(upd smee.DeviceStateUpdate) {
+			backoff = streamMinBackoff
+			m.applyDeviceState(ctx, upd)
+		})
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		m.Log.Warn("device state watch failed, restarting", zap.Error(err), zap.Duration("backoff", backoff))
+
+		timer := time.NewTimer(backoff)
 		select {
 		case <-ctx.Done():
+			timer.Stop()
 			return ctx.Err()
-		case <-ticker.C:
-			// figure out which devices should be alerting
-			res, err := m.DeviceStateStore.RunAlertQueries(ctx)
-			if err != nil {
-				// TODO log
-				continue
+		case <-timer.C:
+		}
+
+		backoff *= 2
+		if backoff > streamMaxBackoff {
+			backoff = streamMaxBackoff
+		}
+	}
+}
+
+// applyDeviceState creates/closes the state alerts on upd's device based on
+// the queries it matches. It counts as one run of the queries for forScans.
+func (m *Manager) applyDeviceState(ctx context.Context, upd smee.DeviceStateUpdate) {
+	dev := smee.Device{
+		ID: upd.Device.ID,
+		Room: smee.Room{
+			ID: upd.Device.Room.ID,
+		},
+	}
+
+	active := make(map[string]smee.Alert)
+	for _, a := range append(m.active.device(dev.Room.ID, dev.ID), m.shadowActive.device(dev.Room.ID, dev.ID)...) {
+		active[a.Type] = a
+	}
+
+	for typ, config := range m.stateAlertConfigs() {
+		alert, ok := active[typ]
+
+		switch {
+		case upd.Matches[typ] && !ok:
+			action := createStateAlert(dev, typ)
+			if m.pendingStateAlert(action, config.ForScans) {
+				m.enqueue(ctx, action)
 			}
+		case !upd.Matches[typ]:
+			m.dropPendingStateAlert(alertKey{roomID: dev.Room.ID, deviceID: dev.ID, typ: typ})
 
-			for typ, devices := range res {
-				// get current open alerts for this query
-				alerts, err := m.IssueStore.ActiveAlertsByType(ctx, typ)
-				if err != nil {
-					// TODO log
-					continue
-				}
-
-				// build a map of the devices that _should_ have an alert
-				shouldAlert := make(map[smee.Device]bool, len(devices))
-				for i := range devices {
-					shouldAlert[key(devices[i])] = true
-				}
-
-				// build a map of the devices that currently have an alert
-				curAlerts := make(map[smee.Device]smee.Alert, len(alerts))
-				for i := range alerts {
-					curAlerts[key(alerts[i].Device)] = alerts[i]
-				}
-
-				// create alerts for every device that should be alerting
-				for dev := range shouldAlert {
-					if _, ok := curAlerts[dev]; ok {
-						// don't need to create an alert if it already exists
-						continue
-					}
-
-					// create the alert
-					alert := smee.Alert{
-						Device: dev,
-						Type:   typ,
-						Start:  time.Now(),
-					}
-
-					m.queue <- alertAction{
-						action: "create",
-						alert:  alert,
-						events: []smee.IssueEvent{
-							{
-								Type:      smee.TypeSystemMessage,
-								Timestamp: time.Now(),
-								Data:      smee.NewSystemMessage(fmt.Sprintf("AV Bot: |%v| %v alert started", dev.ID, typ)),
-							},
-						},
-					}
-				}
-
-				// close alerts for every device that are no longer alerting
-				for device, alert := range curAlerts {
-					if shouldAlert[device] {
-						continue
-					}
-
-					// close the alert
-					m.queue <- alertAction{
-						action: "close",
-						alert:  alert,
-						events: []smee.IssueEvent{
-							{
-								Type:      smee.TypeSystemMessage,
-								Timestamp: time.Now(),
-								Data:      smee.NewSystemMessage(fmt.Sprintf("AV Bot: |%v| %v alert ended", device.ID, typ)),
-							},
-						},
-					}
-				}
+			if ok {
+				m.enqueue(ctx, closeStateAlert(alert))
 			}
 		}
 	}
 }
+
+func createStateAlert(dev smee.Device, typ string) alertAction {
+	return alertAction{
+		action: "create",
+		alert: smee.Alert{
+			Device: dev,
+			Type:   typ,
+			Start:  time.Now(),
+		},
+		events: []smee.IssueEvent{
+			{
+				Type:      smee.TypeSystemMessage,
+				Timestamp: time.Now(),
+				Data:      smee.NewSystemMessage(fmt.Sprintf("AV Bot: |%v| %v alert started", dev.ID, typ)),
+			},
+		},
+	}
+}
+
+func closeStateAlert(alert smee.Alert) alertAction {
+	return alertAction{
+		action: "close",
+		alert:  alert,
+		events: []smee.IssueEvent{
+			{
+				Type:      smee.TypeSystemMessage,
+				Timestamp: time.Now(),
+				Data:      smee.NewSystemMessage(fmt.Sprintf("AV Bot: |%v| %v alert ended", alert.Device.ID, alert.Type)),
+			},
+		},
+	}
+}
@@ -0,0 +1,207 @@
+package alertmanager
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/byuoitav/smee/internal/smee"
+	"go.uber.org/zap"
+)
+
+const (
+	streamMinBackoff = time.Second
+	streamMaxBackoff = time.Minute
+
+	// streamOutageAlertType is the alert type the manager raises on its own
+	// device when the event stream has been down for too long
+	streamOutageAlertType = "event-stream-down"
+)
+
+// streamHealth tracks outages of the event stream across every consumer of it
+type streamHealth struct {
+	mu sync.Mutex
+
+	// down is the time each consumer lost the stream. a consumer is
+	// removed once it is receiving events again.
+	down map[string]time.Time
+
+	// outages is the number of times the stream has gone down
+	outages int
+
+	// alerted is true if an outage alert has been queued for the current outage
+	alerted bool
+}
+
+// streamEvents calls handle with every event from the event stream until ctx
+// is done. If the stream can't be started or fails, it is restarted with
+// exponential backoff; errors from the stream never stop the manager.
+func (m *Manager) streamEvents(ctx context.Context, name string, handle func(context.Context, smee.Event)) error {
+	backoff := streamMinBackoff
+
+	for {
+		err := m.consumeStream(ctx, name, handle, func() {
+			backoff = streamMinBackoff
+		})
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		outages := m.streamDown(name)
+		m.Log.Warn("event stream failed, reconnecting", zap.String("consumer", name), zap.Error(err), zap.Duration("backoff", backoff), zap.Int("outages", outages))
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		backoff *= 2
+		if backoff > streamMaxBackoff {
+			backoff = streamMaxBackoff
+		}
+	}
+}
+
+// consumeStream opens a stream and passes its events to handle until it fails.
+// connected is called after each event that is received.
+func (m *Manager) consumeStream(ctx context.Context, name string, handle func(context.Context, smee.Event), connected func()) error {
+	stream, err := m.EventStreamer.Stream(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to start event stream: %w", err)
+	}
+	defer stream.Close()
+
+	for {
+		event, err := stream.Next(ctx)
+		if err != nil {
+			return fmt.Errorf("unable to get next event: %w", err)
+		}
+
+		connected()
+		m.streamUp(name)
+		handle(ctx, event)
+	}
+}
+
+// streamDown records that consumer lost the event stream, and returns the
+// total number of outages so far
+func (m *Manager) streamDown(consumer string) int {
+	m.health.mu.Lock()
+	defer m.health.mu.Unlock()
+
+	if len(m.health.down) == 0 {
+		m.health.outages++
+		streamOutages.Inc()
+	}
+
+	if _, ok := m.health.down[consumer]; !ok {
+		m.health.down[consumer] = time.Now()
+	}
+
+	return m.health.outages
+}
+
+// streamUp records that consumer is receiving events
+func (m *Manager) streamUp(consumer string) {
+	m.health.mu.Lock()
+	defer m.health.mu.Unlock()
+
+	since, ok := m.health.down[consumer]
+	if !ok {
+		return
+	}
+
+	delete(m.health.down, consumer)
+	m.Log.Info("event stream recovered", zap.String("consumer", consumer), zap.Duration("downFor", time.Since(since)))
+}
+
+// streamDownSince returns the earliest time a consumer lost the event stream,
+// and false if every consumer is receiving events
+func (m *Manager) streamDownSince() (time.Time, bool) {
+	m.health.mu.Lock()
+	defer m.health.mu.Unlock()
+
+	var since time.Time
+	for _, t := range m.health.down {
+		if since.IsZero() || t.Before(since) {
+			since = t
+		}
+	}
+
+	return since, !since.IsZero()
+}
+
+// manageStreamOutages raises an alert on the manager's own device when the
+// event stream has been down for longer than StreamOutageAlertAfter, and
+// closes it once the stream recovers.
+func (m *Manager) manageStreamOutages(ctx context.Context) error {
+	if m.SelfMonitorDevice.ID == "" || m.StreamOutageAlertAfter <= 0 {
+		return nil
+	}
+
+	ticker := time.NewTicker(15 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case now := <-ticker.C:
+			since, down := m.streamDownSince()
+			raise := down && now.Sub(since) >= m.StreamOutageAlertAfter
+
+			m.health.mu.Lock()
+			alerted := m.health.alerted
+			m.health.alerted = raise || (alerted && down)
+			m.health.mu.Unlock()
+
+			switch {
+			case raise && !alerted:
+				m.Log.Error("event stream has been down too long, raising alert", zap.Time("since", since))
+
+				m.enqueue(ctx, alertAction{
+					action: "create",
+					alert: smee.Alert{
+						Device: m.SelfMonitorDevice,
+						Type:   streamOutageAlertType,
+						Start:  now,
+					},
+					events: []smee.IssueEvent{
+						{
+							Type:      smee.TypeSystemMessage,
+							Timestamp: now,
+							Data:      smee.NewSystemMessage(fmt.Sprintf("AV Bot: event stream has been down since %v. Event alerts are not being created or closed", since.Format(time.Kitchen))),
+						},
+					},
+				})
+			case !down && alerted:
+				m.closeStreamOutageAlert(ctx)
+			}
+		}
+	}
+}
+
+// closeStreamOutageAlert queues a close action for the active outage alert
+func (m *Manager) closeStreamOutageAlert(ctx context.Context) {
+	for _, alert := range m.active.device(m.SelfMonitorDevice.Room.ID, m.SelfMonitorDevice.ID) {
+		if alert.Type != streamOutageAlertType {
+			continue
+		}
+
+		m.enqueue(ctx, alertAction{
+			action: "close",
+			alert:  alert,
+			events: []smee.IssueEvent{
+				{
+					Type:      smee.TypeSystemMessage,
+					Timestamp: time.Now(),
+					Data:      smee.NewSystemMessage("AV Bot: event stream recovered"),
+				},
+			},
+		})
+	}
+}
@@ -0,0 +1,81 @@
+// Package eventlog records hub events to JSON lines files, and replays them
+// as an event stream so that alert rules can be tested against real traffic.
+package eventlog
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/byuoitav/smee/internal/smee"
+)
+
+// Record is a single line in an event log
+type Record struct {
+	Timestamp time.Time `json:"timestamp"`
+	RoomID    string    `json:"roomID"`
+	DeviceID  string    `json:"deviceID"`
+	Key       string    `json:"key"`
+	Value     string    `json:"value"`
+}
+
+func newRecord(t time.Time, event smee.Event) Record {
+	return Record{
+		Timestamp: t,
+		RoomID:    event.RoomID,
+		DeviceID:  event.DeviceID,
+		Key:       event.Key,
+		Value:     event.Value,
+	}
+}
+
+// Event returns the event r recorded
+func (r Record) Event() smee.Event {
+	return smee.Event{
+		RoomID:   r.RoomID,
+		DeviceID: r.DeviceID,
+		Key:      r.Key,
+		Value:    r.Value,
+	}
+}
+
+// Reader reads records from an event log
+type Reader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func NewReader(r io.Reader) *Reader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	return &Reader{
+		scanner: scanner,
+	}
+}
+
+// Read returns the next record in the log. Blank lines are skipped. Read
+// returns io.EOF once every record has been read.
+func (r *Reader) Read() (Record, error) {
+	for r.scanner.Scan() {
+		r.line++
+		if len(r.scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var rec Record
+		if err := json.Unmarshal(r.scanner.Bytes(), &rec); err != nil {
+			return Record{}, fmt.Errorf("line %d: unable to decode record: %w", r.line, err)
+		}
+
+		return rec, nil
+	}
+
+	if err := r.scanner.Err(); err != nil {
+		return Record{}, fmt.Errorf("unable to read: %w", err)
+	}
+
+	return Record{}, io.EOF
+}
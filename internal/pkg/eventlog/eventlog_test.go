@@ -0,0 +1,121 @@
+package eventlog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/byuoitav/smee/internal/smee"
+	"github.com/matryer/is"
+)
+
+type fakeStreamer struct {
+	events []smee.Event
+}
+
+func (f *fakeStreamer) Stream(ctx context.Context) (smee.EventStream, error) {
+	return f, nil
+}
+
+func (f *fakeStreamer) Next(ctx context.Context) (smee.Event, error) {
+	if len(f.events) == 0 {
+		return smee.Event{}, errors.New("no more events")
+	}
+
+	event := f.events[0]
+	f.events = f.events[1:]
+	return event, nil
+}
+
+func (f *fakeStreamer) Close() error {
+	return nil
+}
+
+func TestRecordReplay(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+
+	events := []smee.Event{
+		{RoomID: "ITB-1101", DeviceID: "ITB-1101-CP1", Key: "power", Value: "on"},
+		{RoomID: "ITB-1101", DeviceID: "ITB-1101-D1", Key: "input", Value: "hdmi1"},
+	}
+
+	rec := &Recorder{
+		EventStreamer: &fakeStreamer{events: events},
+		Path:          path,
+	}
+
+	stream, err := rec.Stream(ctx)
+	is.NoErr(err)
+
+	for range events {
+		_, err := stream.Next(ctx)
+		is.NoErr(err)
+	}
+	is.NoErr(rec.Close())
+
+	rep := &Replayer{Path: path}
+	replay, err := rep.Stream(ctx)
+	is.NoErr(err)
+
+	for _, want := range events {
+		got, err := replay.Next(ctx)
+		is.NoErr(err)
+		is.Equal(got, want)
+	}
+
+	// the log is only replayed once
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+
+	_, err = replay.Next(ctx)
+	is.True(errors.Is(err, context.DeadlineExceeded))
+
+	select {
+	case <-rep.Done():
+	default:
+		t.Fatal("replayer should be done")
+	}
+}
+
+func TestReplayKeepsEventOnTimeout(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+
+	now := time.Now()
+	f, err := os.Create(path)
+	is.NoErr(err)
+
+	enc := json.NewEncoder(f)
+	is.NoErr(enc.Encode(Record{Timestamp: now, RoomID: "ITB-1101", DeviceID: "ITB-1101-CP1", Key: "power", Value: "on"}))
+	is.NoErr(enc.Encode(Record{Timestamp: now.Add(50 * time.Millisecond), RoomID: "ITB-1101", DeviceID: "ITB-1101-CP1", Key: "power", Value: "standby"}))
+	is.NoErr(f.Close())
+
+	rep := &Replayer{Path: path, Speed: 1}
+	replay, err := rep.Stream(ctx)
+	is.NoErr(err)
+
+	got, err := replay.Next(ctx)
+	is.NoErr(err)
+	is.Equal(got.Value, "on")
+
+	// give up waiting for the second event before it is due
+	short, cancel := context.WithTimeout(ctx, 5*time.Millisecond)
+	defer cancel()
+
+	_, err = replay.Next(short)
+	is.True(errors.Is(err, context.DeadlineExceeded))
+
+	ctx, cancel = context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	got, err = replay.Next(ctx)
+	is.NoErr(err)
+	is.Equal(got.Value, "standby")
+}
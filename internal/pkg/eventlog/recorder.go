@@ -0,0 +1,113 @@
+package eventlog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/byuoitav/smee/internal/smee"
+	"go.uber.org/zap"
+)
+
+// Recorder is an EventStreamer that appends every event its streams receive
+// to the file at Path. Failing to record an event is logged but doesn't fail
+// the stream, so recording never gets in the way of alerting.
+//
+// Every stream is recorded, so wrap the base EventStreamer (not a
+// streamwrapper.StreamWrapper) to record each event once.
+type Recorder struct {
+	EventStreamer smee.EventStreamer
+	Path          string
+	Log           *zap.Logger
+
+	mu   sync.Mutex
+	file *os.File
+}
+
+type recordedStream struct {
+	smee.EventStream
+	recorder *Recorder
+}
+
+func (r *Recorder) Stream(ctx context.Context) (smee.EventStream, error) {
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+
+	stream, err := r.EventStreamer.Stream(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &recordedStream{
+		EventStream: stream,
+		recorder:    r,
+	}, nil
+}
+
+func (s *recordedStream) Next(ctx context.Context) (smee.Event, error) {
+	event, err := s.EventStream.Next(ctx)
+	if err != nil {
+		return event, err
+	}
+
+	if err := s.recorder.record(newRecord(time.Now(), event)); err != nil {
+		s.recorder.Log.Warn("unable to record event", zap.Error(err))
+	}
+
+	return event, nil
+}
+
+func (r *Recorder) open() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file != nil {
+		return nil
+	}
+
+	file, err := os.OpenFile(r.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("unable to open event log: %w", err)
+	}
+
+	r.file = file
+	return nil
+}
+
+func (r *Recorder) record(rec Record) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("unable to marshal record: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return fmt.Errorf("event log is closed")
+	}
+
+	if _, err := r.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("unable to write record: %w", err)
+	}
+
+	return nil
+}
+
+// Close closes the event log. Events received after Close aren't recorded.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
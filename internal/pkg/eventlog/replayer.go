@@ -0,0 +1,183 @@
+package eventlog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/byuoitav/smee/internal/smee"
+)
+
+// Replayer is an EventStreamer that replays the event log at Path, keeping
+// the time between events. The log is only replayed once: every stream
+// shares the same position in the log, so a stream that is reopened picks
+// up where the last one left off. Once the whole log has been replayed,
+// Next blocks until its context is done and Done is closed.
+type Replayer struct {
+	Path string
+
+	// Speed is how many times faster than real time to replay the log.
+	// Zero or less replays events as fast as they can be read.
+	Speed float64
+
+	initOnce sync.Once
+	done     chan struct{}
+
+	openOnce sync.Once
+	openErr  error
+
+	mu     sync.Mutex
+	file   *os.File
+	reader *Reader
+
+	// pending is the next record to replay. It is only taken off once a
+	// stream returns it, so that it isn't lost if the stream gives up
+	// waiting for its replay time.
+	pending *Record
+
+	// first is the timestamp of the first record, and start is when it was replayed
+	first time.Time
+	start time.Time
+}
+
+type replayStream struct {
+	replayer *Replayer
+	closed   chan struct{}
+	once     sync.Once
+}
+
+func (r *Replayer) Stream(ctx context.Context) (smee.EventStream, error) {
+	r.init()
+	r.openOnce.Do(func() {
+		r.file, r.openErr = os.Open(r.Path)
+		if r.openErr != nil {
+			r.openErr = fmt.Errorf("unable to open event log: %w", r.openErr)
+			return
+		}
+
+		r.reader = NewReader(r.file)
+	})
+
+	if r.openErr != nil {
+		return nil, r.openErr
+	}
+
+	return &replayStream{
+		replayer: r,
+		closed:   make(chan struct{}),
+	}, nil
+}
+
+// Done is closed once every event in the log has been replayed
+func (r *Replayer) Done() <-chan struct{} {
+	r.init()
+	return r.done
+}
+
+func (r *Replayer) init() {
+	r.initOnce.Do(func() {
+		r.done = make(chan struct{})
+	})
+}
+
+func (s *replayStream) Next(ctx context.Context) (smee.Event, error) {
+	r := s.replayer
+
+	for {
+		r.mu.Lock()
+		rec, err := r.peek()
+		r.mu.Unlock()
+
+		switch {
+		case errors.Is(err, io.EOF):
+			select {
+			case <-ctx.Done():
+				return smee.Event{}, ctx.Err()
+			case <-s.closed:
+				return smee.Event{}, errors.New("stream closed")
+			}
+		case err != nil:
+			return smee.Event{}, err
+		}
+
+		if wait := time.Until(r.replayAt(rec.Timestamp)); wait > 0 {
+			timer := time.NewTimer(wait)
+
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return smee.Event{}, ctx.Err()
+			case <-s.closed:
+				timer.Stop()
+				return smee.Event{}, errors.New("stream closed")
+			}
+		}
+
+		// another stream may have returned rec while this one waited
+		r.mu.Lock()
+		taken := r.pending == rec
+		if taken {
+			r.pending = nil
+		}
+		r.mu.Unlock()
+
+		if taken {
+			return rec.Event(), nil
+		}
+	}
+}
+
+func (s *replayStream) Close() error {
+	s.once.Do(func() {
+		close(s.closed)
+	})
+
+	return nil
+}
+
+// peek returns the next record without taking it off. r.mu must be held.
+func (r *Replayer) peek() (*Record, error) {
+	if r.pending != nil {
+		return r.pending, nil
+	}
+
+	if r.reader == nil {
+		return nil, io.EOF
+	}
+
+	rec, err := r.reader.Read()
+	switch {
+	case errors.Is(err, io.EOF):
+		r.file.Close()
+		r.reader = nil
+		close(r.done)
+		return nil, io.EOF
+	case err != nil:
+		return nil, err
+	}
+
+	if r.start.IsZero() {
+		r.first = rec.Timestamp
+		r.start = time.Now()
+	}
+
+	r.pending = &rec
+	return r.pending, nil
+}
+
+// replayAt returns when the event recorded at t should be replayed
+func (r *Replayer) replayAt(t time.Time) time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.Speed <= 0 {
+		return time.Time{}
+	}
+
+	return r.start.Add(time.Duration(float64(t.Sub(r.first)) / r.Speed))
+}
@@ -0,0 +1,542 @@
+// Package expr is a small expression language for matching JSON documents,
+// like the device state documents in redis. An expression is made of
+//
+//	field names      device-type, field-state-received.websocket-count
+//	literals         109, 2.5, "display", `raw string`, true, false, 6m
+//	comparisons      == != < <= > >=
+//	regexp matches   =~ !~ (against a string literal)
+//	logic            && || ! ( )
+//	functions        since(field) is how long ago a time field was
+//	parameters       $name, set with Bind
+//
+// For example
+//
+//	device-type == "display" && temperature > 109
+//	since(last-heartbeat) > 6m && deviceID =~ "-(CP|DMPS)[0-9]*$"
+//
+// A field that isn't in the document compares as the zero value of the other
+// side (0, "", false), and since() of a missing field is forever.
+package expr
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Expr is a parsed expression
+type Expr struct {
+	src    string
+	root   node
+	params []string
+	bound  map[string]interface{}
+}
+
+// Parse parses and type checks src. The values of its parameters aren't
+// known until it is bound, so they are only type checked by Bind.
+func Parse(src string) (*Expr, error) {
+	return parse(src, nil)
+}
+
+// Bind returns a copy of e with its parameters set to params, type checked
+// with their values. Each value must be a bool, float64, string, or
+// time.Duration (see ParseValue). Every parameter must be set, and params
+// can't have values for parameters e doesn't have.
+func (e *Expr) Bind(params map[string]interface{}) (*Expr, error) {
+	for name := range params {
+		if !e.hasParam(name) {
+			return nil, fmt.Errorf("$%s isn't used", name)
+		}
+	}
+
+	for _, name := range e.params {
+		if _, ok := params[name]; !ok {
+			return nil, fmt.Errorf("$%s isn't set", name)
+		}
+	}
+
+	return parse(e.src, params)
+}
+
+// Params returns the names of e's parameters
+func (e *Expr) Params() []string {
+	return e.params
+}
+
+func (e *Expr) hasParam(name string) bool {
+	for _, p := range e.params {
+		if p == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ParseValue parses s as a literal: a number, a duration, true or false, or
+// else a string
+func ParseValue(s string) interface{} {
+	if num, err := strconv.ParseFloat(s, 64); err == nil {
+		return num
+	}
+
+	if dur, err := time.ParseDuration(s); err == nil {
+		return dur
+	}
+
+	if s == "true" || s == "false" {
+		return s == "true"
+	}
+
+	return s
+}
+
+func parse(src string, params map[string]interface{}) (*Expr, error) {
+	toks, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{toks: toks, params: params, used: make(map[string]bool)}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, fmt.Errorf("column %d: unexpected %s", tok.pos+1, tok.text)
+	}
+
+	if k := root.kind(); k != kindBool && k != kindAny {
+		return nil, fmt.Errorf("expression is a %s, not a bool", k)
+	}
+
+	var names []string
+	for name := range p.used {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return &Expr{src: src, root: root, params: names, bound: params}, nil
+}
+
+// String returns the source of e, with the values of its bound parameters
+func (e *Expr) String() string {
+	if len(e.bound) == 0 {
+		return e.src
+	}
+
+	var vals []string
+	for _, name := range e.params {
+		vals = append(vals, fmt.Sprintf("$%s=%v", name, e.bound[name]))
+	}
+
+	return e.src + " (" + strings.Join(vals, ", ") + ")"
+}
+
+// Matches returns true if doc matches e at now. An error is returned if a
+// field has the wrong type for how it is used, like comparing a string to a
+// number.
+func (e *Expr) Matches(doc map[string]interface{}, now time.Time) (bool, error) {
+	val, err := e.root.eval(env{doc: doc, now: now})
+	if err != nil {
+		return false, err
+	}
+
+	return truthy(val)
+}
+
+// NextChange returns the earliest time after now that e's result on doc can
+// change without doc changing, because a since() comparison crosses its
+// threshold. ok is false if e's result can't change as time passes.
+func (e *Expr) NextChange(doc map[string]interface{}, now time.Time) (next time.Time, ok bool) {
+	env := env{doc: doc, now: now}
+
+	var walk func(n node)
+	walk = func(n node) {
+		switch n := n.(type) {
+		case not:
+			walk(n.arg)
+		case logical:
+			walk(n.left)
+			walk(n.right)
+		case compare:
+			t, changes := crossesAt(env, n.left, n.right)
+			if !changes {
+				t, changes = crossesAt(env, n.right, n.left)
+			}
+
+			if changes && t.After(now) && (!ok || t.Before(next)) {
+				next, ok = t, true
+			}
+		}
+	}
+
+	walk(e.root)
+	return next, ok
+}
+
+// crossesAt returns when s, if it is a since(), is equal to threshold, if it
+// is a duration literal
+func crossesAt(e env, s, threshold node) (time.Time, bool) {
+	sn, ok := s.(since)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	lit, ok := threshold.(literal)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	d, ok := lit.val.(time.Duration)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	val, err := sn.arg.eval(e)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	str, ok := val.(string)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, str)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	// since(t) > d only becomes true just after t+d
+	return t.Add(d).Add(time.Nanosecond), true
+}
+
+type kind int
+
+const (
+	kindAny kind = iota
+	kindBool
+	kindNumber
+	kindString
+	kindDuration
+)
+
+func (k kind) String() string {
+	switch k {
+	case kindBool:
+		return "bool"
+	case kindNumber:
+		return "number"
+	case kindString:
+		return "string"
+	case kindDuration:
+		return "duration"
+	default:
+		return "value"
+	}
+}
+
+type env struct {
+	doc map[string]interface{}
+	now time.Time
+}
+
+type node interface {
+	// kind is the type of the node's value, if it is known before it is evaluated
+	kind() kind
+	eval(env) (interface{}, error)
+}
+
+type literal struct {
+	val interface{}
+}
+
+func (n literal) kind() kind {
+	return kindOf(n.val)
+}
+
+func (n literal) eval(env) (interface{}, error) {
+	return n.val, nil
+}
+
+// field is a (possibly nested) field of the document
+type field struct {
+	path []string
+}
+
+func (n field) kind() kind {
+	return kindAny
+}
+
+func (n field) eval(e env) (interface{}, error) {
+	var cur interface{} = e.doc
+	for _, name := range n.path {
+		obj, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, nil
+		}
+
+		cur = obj[name]
+	}
+
+	switch v := cur.(type) {
+	case nil, bool, float64, string:
+		return v, nil
+	case int:
+		return float64(v), nil
+	default:
+		return nil, fmt.Errorf("%s is a %T", strings.Join(n.path, "."), v)
+	}
+}
+
+// param is a parameter that hasn't been bound
+type param struct {
+	name string
+}
+
+func (n param) kind() kind {
+	return kindAny
+}
+
+func (n param) eval(env) (interface{}, error) {
+	return nil, fmt.Errorf("$%s isn't set", n.name)
+}
+
+// since is how long before now the time in arg was
+type since struct {
+	arg node
+}
+
+func (n since) kind() kind {
+	return kindDuration
+}
+
+func (n since) eval(e env) (interface{}, error) {
+	val, err := n.arg.eval(e)
+	if err != nil {
+		return nil, err
+	}
+
+	switch v := val.(type) {
+	case nil:
+		return time.Duration(math.MaxInt64), nil
+	case string:
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return nil, fmt.Errorf("since: %q is not a time", v)
+		}
+
+		return e.now.Sub(t), nil
+	default:
+		return nil, fmt.Errorf("since: %v is not a time", v)
+	}
+}
+
+type not struct {
+	arg node
+}
+
+func (n not) kind() kind {
+	return kindBool
+}
+
+func (n not) eval(e env) (interface{}, error) {
+	val, err := n.arg.eval(e)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := truthy(val)
+	if err != nil {
+		return nil, err
+	}
+
+	return !b, nil
+}
+
+// logical is && or ||. The right side is only evaluated if it is needed.
+type logical struct {
+	op          string
+	left, right node
+}
+
+func (n logical) kind() kind {
+	return kindBool
+}
+
+func (n logical) eval(e env) (interface{}, error) {
+	val, err := n.left.eval(e)
+	if err != nil {
+		return nil, err
+	}
+
+	left, err := truthy(val)
+	switch {
+	case err != nil:
+		return nil, err
+	case n.op == "&&" && !left:
+		return false, nil
+	case n.op == "||" && left:
+		return true, nil
+	}
+
+	val, err = n.right.eval(e)
+	if err != nil {
+		return nil, err
+	}
+
+	return truthy(val)
+}
+
+type compare struct {
+	op          string
+	left, right node
+}
+
+func (n compare) kind() kind {
+	return kindBool
+}
+
+func (n compare) eval(e env) (interface{}, error) {
+	left, err := n.left.eval(e)
+	if err != nil {
+		return nil, err
+	}
+
+	right, err := n.right.eval(e)
+	if err != nil {
+		return nil, err
+	}
+
+	if left == nil {
+		left = zero(right)
+	}
+
+	if right == nil {
+		right = zero(left)
+	}
+
+	if kindOf(left) != kindOf(right) {
+		return nil, fmt.Errorf("can't compare %s %v to %s %v", kindOf(left), left, kindOf(right), right)
+	}
+
+	switch n.op {
+	case "==":
+		return left == right, nil
+	case "!=":
+		return left != right, nil
+	}
+
+	var cmp int
+	switch l := left.(type) {
+	case float64:
+		cmp = compareOrdered(l < right.(float64), l > right.(float64))
+	case string:
+		cmp = strings.Compare(l, right.(string))
+	case time.Duration:
+		cmp = compareOrdered(l < right.(time.Duration), l > right.(time.Duration))
+	default:
+		return nil, fmt.Errorf("can't use %s on %s", n.op, kindOf(left))
+	}
+
+	switch n.op {
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	default:
+		return cmp >= 0, nil
+	}
+}
+
+// match is =~ or !~
+type match struct {
+	negate bool
+	left   node
+	re     *regexp.Regexp
+}
+
+func (n match) kind() kind {
+	return kindBool
+}
+
+func (n match) eval(e env) (interface{}, error) {
+	val, err := n.left.eval(e)
+	if err != nil {
+		return nil, err
+	}
+
+	var str string
+	switch v := val.(type) {
+	case nil:
+	case string:
+		str = v
+	case float64:
+		str = strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return nil, fmt.Errorf("can't match %s %v against a regular expression", kindOf(v), v)
+	}
+
+	return n.re.MatchString(str) != n.negate, nil
+}
+
+func kindOf(val interface{}) kind {
+	switch val.(type) {
+	case bool:
+		return kindBool
+	case float64:
+		return kindNumber
+	case string:
+		return kindString
+	case time.Duration:
+		return kindDuration
+	default:
+		return kindAny
+	}
+}
+
+// zero returns the zero value of val's type
+func zero(val interface{}) interface{} {
+	switch val.(type) {
+	case bool:
+		return false
+	case float64:
+		return float64(0)
+	case string:
+		return ""
+	case time.Duration:
+		return time.Duration(0)
+	default:
+		return nil
+	}
+}
+
+func truthy(val interface{}) (bool, error) {
+	switch v := val.(type) {
+	case nil:
+		return false, nil
+	case bool:
+		return v, nil
+	default:
+		return false, fmt.Errorf("%s %v is not a bool", kindOf(v), v)
+	}
+}
+
+func compareOrdered(less, greater bool) int {
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,160 @@
+package expr
+
+import (
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+)
+
+func TestMatches(t *testing.T) {
+	now := time.Date(2021, 3, 4, 12, 0, 0, 0, time.UTC)
+	doc := map[string]interface{}{
+		"deviceID":        "ITB-1101-CP1",
+		"device-type":     "control-processor",
+		"temperature":     float64(110),
+		"websocket-count": float64(0),
+		"last-heartbeat":  now.Add(-7 * time.Minute).Format(time.RFC3339Nano),
+		"field-state-received": map[string]interface{}{
+			"websocket-count": now.Add(-time.Minute).Format(time.RFC3339Nano),
+		},
+	}
+
+	tests := []struct {
+		src  string
+		want bool
+	}{
+		{`device-type == "control-processor" && temperature > 109`, true},
+		{`device-type == "display" || temperature >= 111`, false},
+		{`!(temperature < 100)`, true},
+		{`since(last-heartbeat) > 6m && deviceID =~ "-(CP|DMPS)[0-9]*$"`, true},
+		{`since(field-state-received.websocket-count) > 3m`, false},
+		{`websocket-count == 0 && deviceID !~ "-D[0-9]+$"`, true},
+		{`lamp-hours > 2850`, false},
+		{`battery-type == ""`, true},
+		{`since(last-state-received) > 10m`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			is := is.New(t)
+
+			e, err := Parse(tt.src)
+			is.NoErr(err)
+
+			got, err := e.Matches(doc, now)
+			is.NoErr(err)
+			is.Equal(got, tt.want)
+		})
+	}
+}
+
+func TestMatchesWrongType(t *testing.T) {
+	is := is.New(t)
+
+	e, err := Parse(`device-type > 5`)
+	is.NoErr(err)
+
+	_, err = e.Matches(map[string]interface{}{"device-type": "display"}, time.Now())
+	is.True(err != nil)
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, src := range []string{
+		``,
+		`temperature >`,
+		`temperature > "hot" &&`,
+		`(temperature > 5`,
+		`5 > "five"`,
+		`temperature + 5`,
+		`deviceID =~ "(CP"`,
+		`deviceID =~ deviceType`,
+		`6m`,
+		`true < false`,
+		`uptime(last-heartbeat) > 5m`,
+		`"unterminated`,
+	} {
+		t.Run(src, func(t *testing.T) {
+			is := is.New(t)
+
+			_, err := Parse(src)
+			is.True(err != nil)
+		})
+	}
+}
+
+func TestNextChange(t *testing.T) {
+	is := is.New(t)
+
+	now := time.Date(2021, 3, 4, 12, 0, 0, 0, time.UTC)
+	heartbeat := now.Add(-2 * time.Minute)
+	doc := map[string]interface{}{
+		"deviceID":            "ITB-1101-CP1",
+		"last-heartbeat":      heartbeat.Format(time.RFC3339Nano),
+		"last-state-received": now.Add(-9 * time.Minute).Format(time.RFC3339Nano),
+	}
+
+	e, err := Parse(`since(last-heartbeat) > 6m || (deviceID =~ "-CP[0-9]+$" && 10m < since(last-state-received))`)
+	is.NoErr(err)
+
+	next, ok := e.NextChange(doc, now)
+	is.True(ok)
+	is.Equal(next, now.Add(time.Minute).Add(time.Nanosecond))
+
+	ok, err = e.Matches(doc, next)
+	is.NoErr(err)
+	is.True(ok)
+
+	_, ok = e.NextChange(doc, now.Add(time.Hour))
+	is.True(!ok)
+
+	e, err = Parse(`temperature > 109`)
+	is.NoErr(err)
+
+	_, ok = e.NextChange(doc, now)
+	is.True(!ok)
+}
+
+func TestBind(t *testing.T) {
+	is := is.New(t)
+
+	now := time.Date(2021, 3, 4, 12, 0, 0, 0, time.UTC)
+	doc := map[string]interface{}{
+		"temperature":    float64(100),
+		"last-heartbeat": now.Add(-7 * time.Minute).Format(time.RFC3339Nano),
+	}
+
+	e, err := Parse(`temperature > $max-temp || since(last-heartbeat) > $offline`)
+	is.NoErr(err)
+	is.Equal(e.Params(), []string{"max-temp", "offline"})
+
+	_, err = e.Matches(doc, now)
+	is.True(err != nil) // parameters aren't set
+
+	bound, err := e.Bind(map[string]interface{}{"max-temp": ParseValue("109"), "offline": ParseValue("10m")})
+	is.NoErr(err)
+
+	ok, err := bound.Matches(doc, now)
+	is.NoErr(err)
+	is.True(!ok)
+
+	next, ok := bound.NextChange(doc, now)
+	is.True(ok)
+	is.Equal(next, now.Add(3*time.Minute).Add(time.Nanosecond))
+
+	bound, err = e.Bind(map[string]interface{}{"max-temp": float64(99), "offline": 10 * time.Minute})
+	is.NoErr(err)
+
+	ok, err = bound.Matches(doc, now)
+	is.NoErr(err)
+	is.True(ok)
+
+	_, err = e.Bind(map[string]interface{}{"max-temp": float64(99)})
+	is.True(err != nil) // $offline isn't set
+
+	_, err = e.Bind(map[string]interface{}{"max-temp": float64(99), "offline": 10 * time.Minute, "other": true})
+	is.True(err != nil) // $other isn't used
+
+	_, err = e.Bind(map[string]interface{}{"max-temp": float64(99), "offline": ParseValue("soon")})
+	is.True(err != nil) // can't compare a duration to a string
+}
@@ -0,0 +1,157 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber
+	tokDuration
+	tokString
+	tokParam
+	tokOp
+	tokLParen
+	tokRParen
+	tokComma
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+
+	num float64
+	dur time.Duration
+	str string
+}
+
+// operators, longest first so that "==" isn't read as "=" "="
+var operators = []string{"&&", "||", "==", "!=", "<=", ">=", "=~", "!~", "<", ">", "!"}
+
+// lex splits src into tokens. Identifiers are field names, which may
+// contain '-' and '.' (to reach into nested objects), like
+// field-state-received.websocket-count.
+func lex(src string) ([]token, error) {
+	var toks []token
+
+	for i := 0; i < len(src); {
+		c := rune(src[i])
+
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '(':
+			toks = append(toks, token{kind: tokLParen, text: "(", pos: i})
+			i++
+		case c == ')':
+			toks = append(toks, token{kind: tokRParen, text: ")", pos: i})
+			i++
+		case c == ',':
+			toks = append(toks, token{kind: tokComma, text: ",", pos: i})
+			i++
+		case c == '"' || c == '`':
+			j := i + 1
+			for j < len(src) && src[j] != src[i] {
+				if src[j] == '\\' && c == '"' {
+					j++
+				}
+
+				j++
+			}
+
+			if j >= len(src) {
+				return nil, fmt.Errorf("column %d: unterminated string", i+1)
+			}
+
+			text := src[i : j+1]
+			str, err := strconv.Unquote(text)
+			if err != nil {
+				return nil, fmt.Errorf("column %d: invalid string %s", i+1, text)
+			}
+
+			toks = append(toks, token{kind: tokString, text: text, pos: i, str: str})
+			i = j + 1
+		case c >= '0' && c <= '9':
+			tok, err := lexNumber(src, i)
+			if err != nil {
+				return nil, err
+			}
+
+			toks = append(toks, tok)
+			i += len(tok.text)
+		case c == '$':
+			j := i + 1
+			for j < len(src) && isIdent(rune(src[j])) {
+				j++
+			}
+
+			if j == i+1 {
+				return nil, fmt.Errorf("column %d: $ must be followed by a parameter name", i+1)
+			}
+
+			toks = append(toks, token{kind: tokParam, text: src[i:j], pos: i, str: src[i+1 : j]})
+			i = j
+		case isIdentStart(c):
+			j := i + 1
+			for j < len(src) && isIdent(rune(src[j])) {
+				j++
+			}
+
+			toks = append(toks, token{kind: tokIdent, text: src[i:j], pos: i})
+			i = j
+		default:
+			op := ""
+			for _, o := range operators {
+				if strings.HasPrefix(src[i:], o) {
+					op = o
+					break
+				}
+			}
+
+			if op == "" {
+				return nil, fmt.Errorf("column %d: unexpected %q", i+1, c)
+			}
+
+			toks = append(toks, token{kind: tokOp, text: op, pos: i})
+			i += len(op)
+		}
+	}
+
+	return append(toks, token{kind: tokEOF, pos: len(src)}), nil
+}
+
+// lexNumber reads a number or a duration (a number followed by a unit, like 90s or 1h30m)
+func lexNumber(src string, i int) (token, error) {
+	j := i
+	for j < len(src) && (src[j] >= '0' && src[j] <= '9' || src[j] == '.' || unicode.IsLetter(rune(src[j]))) {
+		j++
+	}
+
+	text := src[i:j]
+	if num, err := strconv.ParseFloat(text, 64); err == nil {
+		return token{kind: tokNumber, text: text, pos: i, num: num}, nil
+	}
+
+	dur, err := time.ParseDuration(text)
+	if err != nil {
+		return token{}, fmt.Errorf("column %d: invalid number or duration %q", i+1, text)
+	}
+
+	return token{kind: tokDuration, text: text, pos: i, dur: dur}, nil
+}
+
+func isIdentStart(c rune) bool {
+	return unicode.IsLetter(c) || c == '_'
+}
+
+func isIdent(c rune) bool {
+	return isIdentStart(c) || unicode.IsDigit(c) || c == '-' || c == '.'
+}
@@ -0,0 +1,236 @@
+package expr
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// parser is a recursive descent parser. From lowest to highest precedence:
+//
+//	or      = and { "||" and }
+//	and     = unary { "&&" unary }
+//	unary   = "!" unary | compare
+//	compare = operand [ op operand ]
+//	operand = literal | field | param | func "(" or ")" | "(" or ")"
+type parser struct {
+	toks []token
+	i    int
+
+	// params are the values of the parameters that are bound
+	params map[string]interface{}
+
+	// used is the set of parameters in the expression
+	used map[string]bool
+}
+
+func (p *parser) peek() token {
+	return p.toks[p.i]
+}
+
+func (p *parser) next() token {
+	tok := p.toks[p.i]
+	if tok.kind != tokEOF {
+		p.i++
+	}
+
+	return tok
+}
+
+func (p *parser) parseOr() (node, error) {
+	return p.parseLogical("||", p.parseAnd)
+}
+
+func (p *parser) parseAnd() (node, error) {
+	return p.parseLogical("&&", p.parseUnary)
+}
+
+func (p *parser) parseLogical(op string, operand func() (node, error)) (node, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().kind == tokOp && p.peek().text == op {
+		tok := p.next()
+
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := checkBool(tok, left, right); err != nil {
+			return nil, err
+		}
+
+		left = logical{op: op, left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if tok := p.peek(); tok.kind == tokOp && tok.text == "!" {
+		p.next()
+
+		arg, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := checkBool(tok, arg); err != nil {
+			return nil, err
+		}
+
+		return not{arg: arg}, nil
+	}
+
+	return p.parseCompare()
+}
+
+func (p *parser) parseCompare() (node, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	tok := p.peek()
+	if tok.kind != tokOp {
+		return left, nil
+	}
+
+	switch tok.text {
+	case "=~", "!~":
+		p.next()
+
+		pattern := p.next()
+		if pattern.kind != tokString {
+			return nil, fmt.Errorf("column %d: %s must be followed by a string", tok.pos+1, tok.text)
+		}
+
+		re, err := regexp.Compile(pattern.str)
+		if err != nil {
+			return nil, fmt.Errorf("column %d: invalid regular expression %s: %w", pattern.pos+1, pattern.text, err)
+		}
+
+		if k := left.kind(); k == kindBool || k == kindDuration {
+			return nil, fmt.Errorf("column %d: can't match a %s against a regular expression", tok.pos+1, k)
+		}
+
+		return match{negate: tok.text == "!~", left: left, re: re}, nil
+	case "==", "!=", "<", "<=", ">", ">=":
+		p.next()
+
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+
+		lk, rk := left.kind(), right.kind()
+		switch {
+		case lk != kindAny && rk != kindAny && lk != rk:
+			return nil, fmt.Errorf("column %d: can't compare a %s to a %s", tok.pos+1, lk, rk)
+		case tok.text != "==" && tok.text != "!=" && (lk == kindBool || rk == kindBool):
+			return nil, fmt.Errorf("column %d: can't use %s on a bool", tok.pos+1, tok.text)
+		}
+
+		return compare{op: tok.text, left: left, right: right}, nil
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseOperand() (node, error) {
+	tok := p.next()
+
+	switch tok.kind {
+	case tokNumber:
+		return literal{val: tok.num}, nil
+	case tokDuration:
+		return literal{val: tok.dur}, nil
+	case tokString:
+		return literal{val: tok.str}, nil
+	case tokParam:
+		p.used[tok.str] = true
+
+		if val, ok := p.params[tok.str]; ok {
+			return literal{val: val}, nil
+		}
+
+		return param{name: tok.str}, nil
+	case tokLParen:
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := p.expect(tokRParen, ")"); err != nil {
+			return nil, err
+		}
+
+		return n, nil
+	case tokIdent:
+		switch {
+		case tok.text == "true":
+			return literal{val: true}, nil
+		case tok.text == "false":
+			return literal{val: false}, nil
+		case p.peek().kind == tokLParen:
+			return p.parseCall(tok)
+		}
+
+		return field{path: strings.Split(tok.text, ".")}, nil
+	case tokEOF:
+		return nil, fmt.Errorf("column %d: unexpected end of expression", tok.pos+1)
+	default:
+		return nil, fmt.Errorf("column %d: unexpected %s", tok.pos+1, tok.text)
+	}
+}
+
+func (p *parser) parseCall(name token) (node, error) {
+	p.next() // (
+
+	arg, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.expect(tokRParen, ")"); err != nil {
+		return nil, err
+	}
+
+	switch name.text {
+	case "since":
+		if k := arg.kind(); k != kindAny && k != kindString {
+			return nil, fmt.Errorf("column %d: since needs a time, not a %s", name.pos+1, k)
+		}
+
+		return since{arg: arg}, nil
+	default:
+		return nil, fmt.Errorf("column %d: unknown function %s", name.pos+1, name.text)
+	}
+}
+
+func (p *parser) expect(kind tokenKind, text string) error {
+	tok := p.next()
+	if tok.kind != kind {
+		if tok.kind == tokEOF {
+			return fmt.Errorf("column %d: expected %s at the end of the expression", tok.pos+1, text)
+		}
+
+		return fmt.Errorf("column %d: expected %s, not %s", tok.pos+1, text, tok.text)
+	}
+
+	return nil
+}
+
+// checkBool returns an error if any of nodes can't be a bool
+func checkBool(op token, nodes ...node) error {
+	for _, n := range nodes {
+		if k := n.kind(); k != kindBool && k != kindAny {
+			return fmt.Errorf("column %d: %s needs a bool, not a %s", op.pos+1, op.text, k)
+		}
+	}
+
+	return nil
+}
@@ -23,6 +23,11 @@ type stream struct {
 func (m *Messenger) Stream(ctx context.Context) (smee.EventStream, error) {
 	mess, err := messenger.BuildMessenger(m.HubURL, base.Messenger, 10000)
 	if err != nil {
+		// the messenger keeps retrying in the background unless it's killed
+		if mess != nil {
+			mess.Kill()
+		}
+
 		return nil, fmt.Errorf("unable to build messenger: %w", err)
 	}
 
@@ -34,7 +39,8 @@ func (m *Messenger) Stream(ctx context.Context) (smee.EventStream, error) {
 }
 
 func (s *stream) Next(ctx context.Context) (smee.Event, error) {
-	event := make(chan events.Event)
+	// buffered so the receiving goroutine doesn't leak if ctx is done first
+	event := make(chan events.Event, 1)
 	go func() {
 		event <- s.m.ReceiveEvent()
 	}()
@@ -56,5 +62,6 @@ func (s *stream) Next(ctx context.Context) (smee.Event, error) {
 
 func (s *stream) Close() error {
 	close(s.done)
+	s.m.Kill()
 	return nil
 }
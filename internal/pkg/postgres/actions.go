@@ -0,0 +1,96 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/byuoitav/smee/internal/smee"
+	"github.com/jackc/pgx/v4"
+)
+
+type alertAction struct {
+	ID        int
+	Action    string
+	Alert     json.RawMessage
+	Events    json.RawMessage
+	CreatedAt time.Time
+}
+
+func (c *Client) AddAlertAction(ctx context.Context, action smee.AlertAction) (smee.AlertAction, error) {
+	alert, err := json.Marshal(action.Alert)
+	if err != nil {
+		return smee.AlertAction{}, fmt.Errorf("unable to marshal alert: %w", err)
+	}
+
+	events, err := json.Marshal(action.Events)
+	if err != nil {
+		return smee.AlertAction{}, fmt.Errorf("unable to marshal events: %w", err)
+	}
+
+	if action.Created.IsZero() {
+		action.Created = time.Now()
+	}
+
+	var id int
+	err = c.pool.QueryRow(ctx,
+		"INSERT INTO alert_actions (action, alert, events, created_at) VALUES ($1, $2, $3, $4) RETURNING id",
+		string(action.Action), alert, events, action.Created).Scan(&id)
+	if err != nil {
+		return smee.AlertAction{}, fmt.Errorf("unable to query/scan: %w", err)
+	}
+
+	action.ID = strconv.Itoa(id)
+	return action, nil
+}
+
+func (c *Client) AlertActions(ctx context.Context) ([]smee.AlertAction, error) {
+	var actions []smee.AlertAction
+	var a alertAction
+
+	_, err := c.pool.QueryFunc(ctx,
+		"SELECT * FROM alert_actions ORDER BY id",
+		nil,
+		[]interface{}{&a.ID, &a.Action, &a.Alert, &a.Events, &a.CreatedAt},
+		func(pgx.QueryFuncRow) error {
+			action := smee.AlertAction{
+				ID:      strconv.Itoa(a.ID),
+				Action:  smee.AlertActionType(a.Action),
+				Created: a.CreatedAt,
+			}
+
+			if err := json.Unmarshal(a.Alert, &action.Alert); err != nil {
+				return fmt.Errorf("unable to unmarshal alert for action %d: %w", a.ID, err)
+			}
+
+			if len(a.Events) > 0 {
+				if err := json.Unmarshal(a.Events, &action.Events); err != nil {
+					return fmt.Errorf("unable to unmarshal events for action %d: %w", a.ID, err)
+				}
+			}
+
+			actions = append(actions, action)
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to queryFunc: %w", err)
+	}
+
+	return actions, nil
+}
+
+func (c *Client) RemoveAlertAction(ctx context.Context, id string) error {
+	actionID, err := strconv.Atoi(id)
+	if err != nil {
+		return fmt.Errorf("unable to parse actionID: %w", err)
+	}
+
+	if _, err := c.pool.Exec(ctx, "DELETE FROM alert_actions WHERE id = $1", actionID); err != nil {
+		return fmt.Errorf("unable to exec: %w", err)
+	}
+
+	return nil
+}
@@ -19,12 +19,17 @@ type alert struct {
 	EndTime         *time.Time
 	AcknowledgedBy  sql.NullString
 	AcknowledgeTime *time.Time
+	ParentAlertID   *int
+	FirstValue      sql.NullString
+	LastValue       sql.NullString
+	LastSeen        *time.Time
+	Occurrences     int
 }
 
 func (c *Client) createAlert(ctx context.Context, tx pgx.Tx, a alert) (alert, error) {
 	err := tx.QueryRow(ctx,
-		"INSERT INTO alerts (issue_id, couch_room_id, couch_device_id, alert_type, start_time) VALUES ($1, $2, $3, $4, $5) RETURNING id",
-		a.IssueID, a.CouchRoomID, a.CouchDeviceID, a.AlertType, a.StartTime).Scan(&a.ID)
+		"INSERT INTO alerts (issue_id, couch_room_id, couch_device_id, alert_type, start_time, first_value, last_value, last_seen, occurrences) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id",
+		a.IssueID, a.CouchRoomID, a.CouchDeviceID, a.AlertType, a.StartTime, a.FirstValue, a.LastValue, a.LastSeen, a.Occurrences).Scan(&a.ID)
 	if err != nil {
 		return alert{}, fmt.Errorf("unable to query/scan: %w", err)
 	}
@@ -46,6 +51,34 @@ func (c *Client) closeAlert(ctx context.Context, tx pgx.Tx, alertID int) error {
 	return nil
 }
 
+func (c *Client) recordAlertOccurrence(ctx context.Context, tx pgx.Tx, issueID, alertID int, value string, seen time.Time) error {
+	res, err := tx.Exec(ctx,
+		"UPDATE alerts SET last_value = $1, last_seen = $2, occurrences = occurrences + 1 WHERE id = $3 AND issue_id = $4 AND end_time IS NULL",
+		value, seen, alertID, issueID)
+	switch {
+	case err != nil:
+		return fmt.Errorf("unable to exec: %w", err)
+	case res.RowsAffected() == 0:
+		return fmt.Errorf("invalid alertID")
+	}
+
+	return nil
+}
+
+func (c *Client) setAlertParent(ctx context.Context, tx pgx.Tx, issueID, parentID, alertID int) error {
+	res, err := tx.Exec(ctx,
+		"UPDATE alerts SET parent_alert_id = $1 WHERE id = $2 AND issue_id = $3",
+		parentID, alertID, issueID)
+	switch {
+	case err != nil:
+		return fmt.Errorf("unable to exec: %w", err)
+	case res.RowsAffected() == 0:
+		return fmt.Errorf("invalid alertID")
+	}
+
+	return nil
+}
+
 func (c *Client) closeAlertsforIssue(ctx context.Context, tx pgx.Tx, issueID int) error {
 	res, err := tx.Exec(ctx,
 		"UPDATE alerts SET end_time = $1 WHERE issue_id = $2 AND end_time IS NULL",
@@ -77,7 +110,7 @@ func (c *Client) queryAlerts(ctx context.Context, tx pgx.Tx, query string, args
 	var a alert
 
 	_, err := tx.QueryFunc(ctx, query, args,
-		[]interface{}{&a.ID, &a.IssueID, &a.CouchRoomID, &a.CouchDeviceID, &a.AlertType, &a.StartTime, &a.EndTime, &a.AcknowledgedBy, &a.AcknowledgeTime},
+		[]interface{}{&a.ID, &a.IssueID, &a.CouchRoomID, &a.CouchDeviceID, &a.AlertType, &a.StartTime, &a.EndTime, &a.AcknowledgedBy, &a.AcknowledgeTime, &a.ParentAlertID, &a.FirstValue, &a.LastValue, &a.LastSeen, &a.Occurrences},
 		func(pgx.QueryFuncRow) error {
 			alerts = append(alerts, alert{
 				ID:              a.ID,
@@ -89,6 +122,11 @@ func (c *Client) queryAlerts(ctx context.Context, tx pgx.Tx, query string, args
 				EndTime:         a.EndTime,
 				AcknowledgedBy:  a.AcknowledgedBy,
 				AcknowledgeTime: a.AcknowledgeTime,
+				ParentAlertID:   a.ParentAlertID,
+				FirstValue:      a.FirstValue,
+				LastValue:       a.LastValue,
+				LastSeen:        a.LastSeen,
+				Occurrences:     a.Occurrences,
 			})
 			return nil
 		},
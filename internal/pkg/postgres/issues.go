@@ -19,6 +19,7 @@ type issue struct {
 	AcknowledgedBy   sql.NullString
 	AcknowledgedTime *time.Time
 	StatusMsg        sql.NullString
+	ParentIssueID    *int
 }
 
 func (c *Client) activeIssueID(ctx context.Context, tx pgx.Tx, roomID string) (int, error) {
@@ -124,12 +125,26 @@ func (c *Client) setIssueStatus(ctx context.Context, tx pgx.Tx, issueID int, sta
 	return nil
 }
 
+func (c *Client) setIssueParent(ctx context.Context, tx pgx.Tx, issueID, parentID int) error {
+	res, err := tx.Exec(ctx,
+		"UPDATE issues SET parent_issue_id = $1 WHERE id = $2",
+		parentID, issueID)
+	switch {
+	case err != nil:
+		return fmt.Errorf("unable to exec: %w", err)
+	case res.RowsAffected() == 0:
+		return fmt.Errorf("invalid issueID")
+	}
+
+	return nil
+}
+
 func (c *Client) issue(ctx context.Context, tx pgx.Tx, id int) (issue, error) {
 	var iss issue
 
 	err := tx.QueryRow(ctx,
 		"SELECT * FROM issues WHERE id = $1",
-		id).Scan(&iss.ID, &iss.CouchRoomID, &iss.StartTime, &iss.EndTime, &iss.AcknowledgedBy, &iss.AcknowledgedTime, &iss.StatusMsg)
+		id).Scan(&iss.ID, &iss.CouchRoomID, &iss.StartTime, &iss.EndTime, &iss.AcknowledgedBy, &iss.AcknowledgedTime, &iss.StatusMsg, &iss.ParentIssueID)
 	if err != nil {
 		return issue{}, fmt.Errorf("unable to get query/scan: %w", err)
 	}
@@ -218,5 +233,9 @@ func buildIssue(iss issue, alerts []alert, incs []incidentMapping, events []issu
 		smeeIss.Status = iss.StatusMsg.String
 	}
 
+	if iss.ParentIssueID != nil {
+		smeeIss.ParentID = strconv.Itoa(*iss.ParentIssueID)
+	}
+
 	return smeeIss, nil
 }
@@ -2,9 +2,11 @@ package postgres
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"strconv"
+	"time"
 
 	"github.com/byuoitav/smee/internal/smee"
 	"github.com/jackc/pgx/v4/pgxpool"
@@ -74,6 +76,13 @@ func (c *Client) CreateAlert(ctx context.Context, smeeAlert smee.Alert) (smee.Is
 		CouchDeviceID: smeeAlert.Device.ID,
 		AlertType:     smeeAlert.Type,
 		StartTime:     smeeAlert.Start,
+		FirstValue:    sql.NullString{String: smeeAlert.FirstValue, Valid: smeeAlert.Count > 0},
+		LastValue:     sql.NullString{String: smeeAlert.LastValue, Valid: smeeAlert.Count > 0},
+		Occurrences:   smeeAlert.Count,
+	}
+
+	if !smeeAlert.LastSeen.IsZero() {
+		a.LastSeen = &smeeAlert.LastSeen
 	}
 
 	a, err = c.createAlert(ctx, tx, a)
@@ -83,6 +92,12 @@ func (c *Client) CreateAlert(ctx context.Context, smeeAlert smee.Alert) (smee.Is
 
 	c.Log.Info("Created alert", zap.String("roomID", a.CouchRoomID), zap.Int("issueID", issID), zap.Int("alertID", a.ID), zap.String("deviceID", a.CouchDeviceID), zap.String("type", a.AlertType))
 
+	// a new alert needs to be acknowledged again. this has to happen
+	// before the transaction is committed, or it can't use tx
+	if err := c.unacknowledgeIssue(ctx, tx, issID); err != nil {
+		return smee.Issue{}, fmt.Errorf("unable to unaknowledge issue: %w", err)
+	}
+
 	smeeIss, err := c.smeeIssue(ctx, tx, issID)
 	if err != nil {
 		return smee.Issue{}, fmt.Errorf("unable to get smeeIssue: %w", err)
@@ -92,10 +107,6 @@ func (c *Client) CreateAlert(ctx context.Context, smeeAlert smee.Alert) (smee.Is
 		return smee.Issue{}, fmt.Errorf("unable to commit transaction: %w", err)
 	}
 
-	if err := c.unacknowledgeIssue(ctx, tx, issID); err != nil {
-		return smee.Issue{}, fmt.Errorf("unable to unaknowledge issue: %w", err)
-	}
-
 	return smeeIss, nil
 }
 
@@ -327,6 +338,115 @@ func (c *Client) LinkIncident(ctx context.Context, issueID string, inc smee.Inci
 	return smeeIss, nil
 }
 
+func (c *Client) RecordAlertOccurrence(ctx context.Context, issueID, alertID, value string, seen time.Time) (smee.Issue, error) {
+	issID, err := strconv.Atoi(issueID)
+	if err != nil {
+		return smee.Issue{}, fmt.Errorf("unable to parse issueID: %w", err)
+	}
+
+	aID, err := strconv.Atoi(alertID)
+	if err != nil {
+		return smee.Issue{}, fmt.Errorf("unable to parse alertID: %w", err)
+	}
+
+	tx, err := c.pool.Begin(ctx)
+	if err != nil {
+		return smee.Issue{}, fmt.Errorf("unable to start transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	if err := c.recordAlertOccurrence(ctx, tx, issID, aID, value, seen); err != nil {
+		return smee.Issue{}, fmt.Errorf("unable to record alert occurrence: %w", err)
+	}
+
+	smeeIss, err := c.smeeIssue(ctx, tx, issID)
+	if err != nil {
+		return smee.Issue{}, fmt.Errorf("unable to get smeeIssue: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return smee.Issue{}, fmt.Errorf("unable to commit transaction: %w", err)
+	}
+
+	return smeeIss, nil
+}
+
+func (c *Client) SetAlertParent(ctx context.Context, issueID, parentID string, alertIDs ...string) (smee.Issue, error) {
+	issID, err := strconv.Atoi(issueID)
+	if err != nil {
+		return smee.Issue{}, fmt.Errorf("unable to parse issueID: %w", err)
+	}
+
+	pID, err := strconv.Atoi(parentID)
+	if err != nil {
+		return smee.Issue{}, fmt.Errorf("unable to parse parentID: %w", err)
+	}
+
+	tx, err := c.pool.Begin(ctx)
+	if err != nil {
+		return smee.Issue{}, fmt.Errorf("unable to start transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	for _, alertID := range alertIDs {
+		aID, err := strconv.Atoi(alertID)
+		if err != nil {
+			return smee.Issue{}, fmt.Errorf("unable to parse alertID: %w", err)
+		}
+
+		if err := c.setAlertParent(ctx, tx, issID, pID, aID); err != nil {
+			return smee.Issue{}, fmt.Errorf("unable to set parent of alert %s: %w", alertID, err)
+		}
+	}
+
+	smeeIss, err := c.smeeIssue(ctx, tx, issID)
+	if err != nil {
+		return smee.Issue{}, fmt.Errorf("unable to get smeeIssue: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return smee.Issue{}, fmt.Errorf("unable to commit transaction: %w", err)
+	}
+
+	return smeeIss, nil
+}
+
+func (c *Client) SetIssueParent(ctx context.Context, parentID string, issueIDs ...string) error {
+	pID, err := strconv.Atoi(parentID)
+	if err != nil {
+		return fmt.Errorf("unable to parse parentID: %w", err)
+	}
+
+	tx, err := c.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to start transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	for _, issueID := range issueIDs {
+		issID, err := strconv.Atoi(issueID)
+		if err != nil {
+			return fmt.Errorf("unable to parse issueID: %w", err)
+		}
+
+		if err := c.setIssueParent(ctx, tx, issID, pID); err != nil {
+			return fmt.Errorf("unable to set parent of issue %s: %w", issueID, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("unable to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
 func (c *Client) AddIssueEvents(ctx context.Context, issueID string, smeeEvents ...smee.IssueEvent) error {
 	issID, err := strconv.Atoi(issueID)
 	if err != nil {
@@ -150,13 +150,24 @@ func convertAlert(a alert) smee.Alert {
 				Name: a.CouchRoomID,
 			},
 		},
-		Type:  a.AlertType,
-		Start: a.StartTime,
+		Type:       a.AlertType,
+		Start:      a.StartTime,
+		FirstValue: a.FirstValue.String,
+		LastValue:  a.LastValue.String,
+		Count:      a.Occurrences,
+	}
+
+	if a.LastSeen != nil {
+		smeeAlert.LastSeen = *a.LastSeen
 	}
 
 	if a.EndTime != nil {
 		smeeAlert.End = *a.EndTime
 	}
 
+	if a.ParentAlertID != nil {
+		smeeAlert.ParentID = strconv.Itoa(*a.ParentAlertID)
+	}
+
 	return smeeAlert
 }
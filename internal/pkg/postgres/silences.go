@@ -0,0 +1,124 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/byuoitav/smee/internal/smee"
+	"github.com/jackc/pgx/v4"
+)
+
+type silence struct {
+	ID        int
+	RoomID    string
+	DeviceID  string
+	AlertType string
+	CreatedBy string
+	Comment   string
+	StartTime time.Time
+	EndTime   time.Time
+}
+
+func (c *Client) Silences(ctx context.Context) ([]smee.Silence, error) {
+	var silences []smee.Silence
+	var s silence
+
+	_, err := c.pool.QueryFunc(ctx,
+		"SELECT * FROM silences WHERE end_time > now() ORDER BY id",
+		nil,
+		[]interface{}{&s.ID, &s.RoomID, &s.DeviceID, &s.AlertType, &s.CreatedBy, &s.Comment, &s.StartTime, &s.EndTime},
+		func(pgx.QueryFuncRow) error {
+			silences = append(silences, convertSilence(s))
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to queryFunc: %w", err)
+	}
+
+	return silences, nil
+}
+
+func (c *Client) Silence(ctx context.Context, id string) (smee.Silence, error) {
+	silenceID, err := strconv.Atoi(id)
+	if err != nil {
+		return smee.Silence{}, fmt.Errorf("unable to parse silenceID: %w", err)
+	}
+
+	var s silence
+	err = c.pool.QueryRow(ctx,
+		"SELECT * FROM silences WHERE id = $1",
+		silenceID).Scan(&s.ID, &s.RoomID, &s.DeviceID, &s.AlertType, &s.CreatedBy, &s.Comment, &s.StartTime, &s.EndTime)
+	switch {
+	case err == pgx.ErrNoRows:
+		return smee.Silence{}, smee.ErrSilenceNotFound
+	case err != nil:
+		return smee.Silence{}, fmt.Errorf("unable to query/scan: %w", err)
+	}
+
+	return convertSilence(s), nil
+}
+
+func (c *Client) CreateSilence(ctx context.Context, s smee.Silence) (smee.Silence, error) {
+	var id int
+	err := c.pool.QueryRow(ctx,
+		"INSERT INTO silences (room_id, device_id, alert_type, created_by, comment, start_time, end_time) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id",
+		s.RoomID, s.DeviceID, s.AlertType, s.CreatedBy, s.Comment, s.Start, s.End).Scan(&id)
+	if err != nil {
+		return smee.Silence{}, fmt.Errorf("unable to query/scan: %w", err)
+	}
+
+	s.ID = strconv.Itoa(id)
+	return s, nil
+}
+
+func (c *Client) UpdateSilence(ctx context.Context, s smee.Silence) (smee.Silence, error) {
+	silenceID, err := strconv.Atoi(s.ID)
+	if err != nil {
+		return smee.Silence{}, fmt.Errorf("unable to parse silenceID: %w", err)
+	}
+
+	tag, err := c.pool.Exec(ctx,
+		"UPDATE silences SET room_id = $2, device_id = $3, alert_type = $4, created_by = $5, comment = $6, start_time = $7, end_time = $8 WHERE id = $1",
+		silenceID, s.RoomID, s.DeviceID, s.AlertType, s.CreatedBy, s.Comment, s.Start, s.End)
+	switch {
+	case err != nil:
+		return smee.Silence{}, fmt.Errorf("unable to exec: %w", err)
+	case tag.RowsAffected() == 0:
+		return smee.Silence{}, smee.ErrSilenceNotFound
+	}
+
+	return s, nil
+}
+
+func (c *Client) DeleteSilence(ctx context.Context, id string) error {
+	silenceID, err := strconv.Atoi(id)
+	if err != nil {
+		return fmt.Errorf("unable to parse silenceID: %w", err)
+	}
+
+	tag, err := c.pool.Exec(ctx, "DELETE FROM silences WHERE id = $1", silenceID)
+	switch {
+	case err != nil:
+		return fmt.Errorf("unable to exec: %w", err)
+	case tag.RowsAffected() == 0:
+		return smee.ErrSilenceNotFound
+	}
+
+	return nil
+}
+
+func convertSilence(s silence) smee.Silence {
+	return smee.Silence{
+		ID:        strconv.Itoa(s.ID),
+		RoomID:    s.RoomID,
+		DeviceID:  s.DeviceID,
+		AlertType: s.AlertType,
+		CreatedBy: s.CreatedBy,
+		Comment:   s.Comment,
+		Start:     s.StartTime,
+		End:       s.EndTime,
+	}
+}
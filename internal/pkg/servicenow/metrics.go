@@ -0,0 +1,44 @@
+package servicenow
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "smee",
+		Subsystem: "servicenow",
+		Name:      "request_duration_seconds",
+		Help:      "How long requests to ServiceNow take, by operation.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation"})
+
+	requestErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "smee",
+		Subsystem: "servicenow",
+		Name:      "request_errors_total",
+		Help:      "The number of requests to ServiceNow that failed or got a non-2xx response, by operation.",
+	}, []string{"operation"})
+)
+
+// do sends req, recording its latency and whether it failed under operation
+func (c *Client) do(req *http.Request, operation string) (*http.Response, error) {
+	start := time.Now()
+	resp, err := c.Client.Do(req)
+	requestDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+
+	switch {
+	case err != nil:
+		requestErrors.WithLabelValues(operation).Inc()
+		return nil, fmt.Errorf("unable to do request: %w", err)
+	case resp.StatusCode/100 != 2:
+		requestErrors.WithLabelValues(operation).Inc()
+	}
+
+	return resp, nil
+}
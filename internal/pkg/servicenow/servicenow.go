@@ -44,9 +44,9 @@ func (c *Client) Incident(ctx context.Context, id string) (Incident, error) {
 		return Incident{}, fmt.Errorf("unable to build request: %w", err)
 	}
 
-	resp, err := c.Client.Do(req)
+	resp, err := c.do(req, "incident")
 	if err != nil {
-		return Incident{}, fmt.Errorf("unable to do request: %w", err)
+		return Incident{}, err
 	}
 	defer resp.Body.Close()
 
@@ -82,9 +82,9 @@ func (c *Client) IncidentByNumber(ctx context.Context, number string) (Incident,
 	query.Add("sysparm_query", fmt.Sprintf("number=%s", number))
 	req.URL.RawQuery = query.Encode()
 
-	resp, err := c.Client.Do(req)
+	resp, err := c.do(req, "incident_by_number")
 	if err != nil {
-		return Incident{}, fmt.Errorf("unable to do request: %w", err)
+		return Incident{}, err
 	}
 	defer resp.Body.Close()
 
@@ -130,9 +130,9 @@ func (c *Client) AddInternalNote(ctx context.Context, id, note string) error {
 
 	req.Header.Add("Content-Type", "application/json")
 
-	resp, err := c.Client.Do(req)
+	resp, err := c.do(req, "add_internal_note")
 	if err != nil {
-		return fmt.Errorf("unable to do request: %w", err)
+		return err
 	}
 	defer resp.Body.Close()
 
@@ -158,9 +158,9 @@ func (c *Client) CreateIncident(ctx context.Context, inc IncidentRequest) (Incid
 
 	req.Header.Add("Content-Type", "application/json")
 
-	resp, err := c.Client.Do(req)
+	resp, err := c.do(req, "create_incident")
 	if err != nil {
-		return Incident{}, fmt.Errorf("unable to do request: %w", err)
+		return Incident{}, err
 	}
 	defer resp.Body.Close()
 
@@ -0,0 +1,22 @@
+package streamwrapper
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	eventsReceived = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "smee",
+		Subsystem: "streamwrapper",
+		Name:      "events_received_total",
+		Help:      "The number of events received from the base stream.",
+	})
+
+	eventsDropped = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "smee",
+		Subsystem: "streamwrapper",
+		Name:      "events_dropped_total",
+		Help:      "The number of events that weren't delivered to a wrapped stream because its buffer was full.",
+	})
+)
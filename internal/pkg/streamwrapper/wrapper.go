@@ -38,7 +38,6 @@ func (s *StreamWrapper) Stream(ctx context.Context) (smee.EventStream, error) {
 		// events to be missed if a receiver is busy
 		events: make(chan smee.Event, 512),
 	}
-	s.streams[wrapped] = struct{}{}
 
 	if !s.streaming {
 		// create a new base stream
@@ -51,6 +50,9 @@ func (s *StreamWrapper) Stream(ctx context.Context) (smee.EventStream, error) {
 		s.streaming = true
 	}
 
+	// only registered once the base stream exists, so that failed
+	// reconnects don't leave streams behind that nobody reads
+	s.streams[wrapped] = struct{}{}
 	return wrapped, nil
 }
 
@@ -60,6 +62,13 @@ func (s *StreamWrapper) startStream(stream smee.EventStream) {
 		s.mu.Lock()
 		defer s.mu.Unlock()
 		s.streaming = false
+
+		// close every wrapped stream so that receivers know the base
+		// stream failed and can start a new one
+		for wrapped := range s.streams {
+			close(wrapped.events)
+			delete(s.streams, wrapped)
+		}
 	}()
 
 	next := func() (smee.Event, error) {
@@ -72,10 +81,12 @@ func (s *StreamWrapper) startStream(stream smee.EventStream) {
 		s.mu.Lock()
 		defer s.mu.Unlock()
 
+		eventsReceived.Inc()
 		for wrapped := range s.streams {
 			select {
 			case wrapped.events <- event:
 			default:
+				eventsDropped.Inc()
 			}
 		}
 
@@ -110,6 +121,12 @@ func (s *wrappedStream) Next(ctx context.Context) (smee.Event, error) {
 func (s *wrappedStream) Close() error {
 	s.wrapper.mu.Lock()
 	defer s.wrapper.mu.Unlock()
+
+	// events was already closed if the base stream failed
+	if _, ok := s.wrapper.streams[s]; !ok {
+		return nil
+	}
+
 	delete(s.wrapper.streams, s)
 	close(s.events)
 	return nil
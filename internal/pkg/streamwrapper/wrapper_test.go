@@ -0,0 +1,30 @@
+package streamwrapper
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/byuoitav/smee/internal/smee"
+	"github.com/matryer/is"
+)
+
+type failingStreamer struct{}
+
+func (failingStreamer) Stream(ctx context.Context) (smee.EventStream, error) {
+	return nil, errors.New("hub is down")
+}
+
+func TestStreamFailureDoesNotLeak(t *testing.T) {
+	is := is.New(t)
+
+	s := &StreamWrapper{EventStreamer: failingStreamer{}}
+	for i := 0; i < 3; i++ {
+		_, err := s.Stream(context.Background())
+		is.True(err != nil)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	is.Equal(len(s.streams), 0)
+}
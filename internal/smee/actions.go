@@ -0,0 +1,36 @@
+package smee
+
+import (
+	"context"
+	"time"
+)
+
+// AlertActionType is what an AlertAction does to its alert
+type AlertActionType string
+
+const (
+	AlertActionCreate AlertActionType = "create"
+	AlertActionClose  AlertActionType = "close"
+)
+
+// AlertAction is a queued request to create or close an alert
+type AlertAction struct {
+	ID      string          `json:"id"`
+	Action  AlertActionType `json:"action"`
+	Alert   Alert           `json:"alert"`
+	Events  []IssueEvent    `json:"events"`
+	Created time.Time       `json:"created"`
+}
+
+// AlertActionStore persists alert actions until they have been run, so that
+// actions aren't lost if the alert manager restarts.
+type AlertActionStore interface {
+	// AddAlertAction stores action and returns it with its ID set
+	AddAlertAction(ctx context.Context, action AlertAction) (AlertAction, error)
+
+	// AlertActions returns every stored action, oldest first
+	AlertActions(ctx context.Context) ([]AlertAction, error)
+
+	// RemoveAlertAction removes an action once it has been run
+	RemoveAlertAction(ctx context.Context, id string) error
+}
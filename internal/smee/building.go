@@ -0,0 +1,44 @@
+package smee
+
+import (
+	"strings"
+	"time"
+)
+
+// BuildingRule raises a building level issue when at least MinRooms rooms in
+// a building get new issues within Within of each other. The building issue
+// has one alert of Type, and the room issues are linked to it. A zero
+// MinRooms disables the rule.
+type BuildingRule struct {
+	Type     string
+	MinRooms int
+	Within   time.Duration
+
+	// Groups is a map of building -> prefixes of the room IDs in it
+	Groups map[string][]string
+}
+
+// Building returns the building roomID is in. Configured groups are checked
+// first (the longest matching prefix wins), then the part of roomID before
+// the first "-" is used, so JFSB-B120 is in JFSB. It returns "" if roomID
+// isn't in a building.
+func (r BuildingRule) Building(roomID string) string {
+	building, matched := "", 0
+	for name, prefixes := range r.Groups {
+		for _, prefix := range prefixes {
+			if len(prefix) > matched && strings.HasPrefix(roomID, prefix) {
+				building, matched = name, len(prefix)
+			}
+		}
+	}
+
+	if building != "" {
+		return building
+	}
+
+	if i := strings.Index(roomID, "-"); i > 0 {
+		return roomID[:i]
+	}
+
+	return ""
+}
@@ -0,0 +1,26 @@
+package smee
+
+import (
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func TestBuildingRuleBuilding(t *testing.T) {
+	is := is.New(t)
+
+	rule := BuildingRule{
+		Groups: map[string][]string{
+			"MARB":  {"MARB-"},
+			"MARB2": {"MARB-2"},
+			"HBLL":  {"HBLL-", "LIB-"},
+		},
+	}
+
+	is.Equal(rule.Building("JFSB-B120"), "JFSB")
+	is.Equal(rule.Building("MARB-101"), "MARB")
+	is.Equal(rule.Building("MARB-201"), "MARB2")
+	is.Equal(rule.Building("LIB-1102"), "HBLL")
+	is.Equal(rule.Building("JFSB"), "")
+	is.Equal(rule.Building("-101"), "")
+}
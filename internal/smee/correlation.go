@@ -0,0 +1,25 @@
+package smee
+
+import "time"
+
+// CorrelationRule opens a single alert of Type in a room when at least
+// MinDevices distinct devices in that room have active alerts of AlertTypes
+// that started within Within of each other. The device alerts are marked
+// as children of the correlated alert, which is closed once they all are.
+type CorrelationRule struct {
+	Type       string
+	AlertTypes []string
+	MinDevices int
+	Within     time.Duration
+}
+
+// Correlates returns true if typ is one of r's alert types
+func (r CorrelationRule) Correlates(typ string) bool {
+	for _, t := range r.AlertTypes {
+		if t == typ {
+			return true
+		}
+	}
+
+	return false
+}
@@ -4,4 +4,7 @@ import "errors"
 
 var (
 	ErrRoomIssueNotFound = errors.New("no active issue found for the given room")
+	ErrSilenceNotFound   = errors.New("silence not found")
+	ErrAlertNotFound     = errors.New("no active alert found")
+	ErrInvalidAlertType  = errors.New("invalid alert type")
 )
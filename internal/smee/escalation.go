@@ -0,0 +1,49 @@
+package smee
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// TypeEscalation is an issue event recording that an escalation step ran
+const TypeEscalation IssueEventType = "escalation"
+
+// EscalationMessage is the data of a TypeEscalation issue event
+type EscalationMessage struct {
+	Message string `json:"msg"`
+	Policy  string `json:"policy"`
+	Step    int    `json:"step"`
+}
+
+func NewEscalationMessage(policy string, step int, msg string) json.RawMessage {
+	data, _ := json.Marshal(EscalationMessage{
+		Message: msg,
+		Policy:  policy,
+		Step:    step,
+	})
+
+	return data
+}
+
+// EscalationPolicy escalates issues that haven't been acknowledged. An empty
+// filter matches every issue.
+type EscalationPolicy struct {
+	Name string
+
+	AlertTypes   []string
+	RoomPrefixes []string
+
+	// Steps are run in order, each once its After has passed
+	Steps []EscalationStep
+}
+
+// EscalationStep is run once an issue has gone unacknowledged for After
+type EscalationStep struct {
+	After time.Duration
+
+	// Notify is the name of a notifier to send the escalation to
+	Notify string
+
+	// CreateIncident creates and links an incident if the issue doesn't have one
+	CreateIncident bool
+}
@@ -0,0 +1,18 @@
+package smee
+
+import "text/template"
+
+// IncidentRule opens an incident automatically when an alert of its type is
+// created on an issue that doesn't already have an incident
+type IncidentRule struct {
+	// ShortDescription is executed with IncidentTemplateData
+	ShortDescription *template.Template
+}
+
+// IncidentTemplateData is the data an IncidentRule's templates are executed with
+type IncidentTemplateData struct {
+	Room      string
+	Device    string
+	Type      string
+	KBArticle string
+}
@@ -16,6 +16,16 @@ type IssueStore interface {
 	AddIssueEvents(ctx context.Context, issueID string, event ...IssueEvent) error
 	LinkIncident(ctx context.Context, issueID string, inc Incident) (Issue, error)
 
+	// SetAlertParent marks each of alertIDs on issueID as a child of parentID
+	SetAlertParent(ctx context.Context, issueID, parentID string, alertIDs ...string) (Issue, error)
+
+	// RecordAlertOccurrence records that alertID's condition was seen again at seen with value
+	RecordAlertOccurrence(ctx context.Context, issueID, alertID, value string, seen time.Time) (Issue, error)
+
+	// SetIssueParent links each of issueIDs to parentID, like the room
+	// issues of a building outage
+	SetIssueParent(ctx context.Context, parentID string, issueIDs ...string) error
+
 	ActiveIssue(ctx context.Context, roomID string) (Issue, error)
 	ActiveIssues(context.Context) ([]Issue, error)
 	CloseAlertsForIssue(ctx context.Context, issueID string) (Issue, error)
@@ -59,6 +69,10 @@ type Issue struct {
 
 	// Issue status
 	Status string `json:"status"`
+
+	// ParentID is the ID of the issue this issue is linked to, like the
+	// building issue of an outage across many rooms
+	ParentID string `json:"parentID,omitempty"`
 }
 
 // Active returns true if this issue is currently active, and false if this
@@ -104,6 +118,20 @@ func (i IssueEvent) ParseData() (interface{}, error) {
 			return nil, fmt.Errorf("unable to parse system message: %w", err)
 		}
 
+		return msg, nil
+	case TypeUserMessage:
+		var msg UserMessage
+		if err := json.Unmarshal(i.Data, &msg); err != nil {
+			return nil, fmt.Errorf("unable to parse user message: %w", err)
+		}
+
+		return msg, nil
+	case TypeEscalation:
+		var msg EscalationMessage
+		if err := json.Unmarshal(i.Data, &msg); err != nil {
+			return nil, fmt.Errorf("unable to parse escalation message: %w", err)
+		}
+
 		return msg, nil
 	default:
 		return nil, errors.New("unknown type")
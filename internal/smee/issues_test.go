@@ -2,6 +2,7 @@ package smee
 
 import (
 	"testing"
+	"time"
 
 	"github.com/matryer/is"
 )
@@ -22,3 +23,36 @@ func TestIssueEventTypeSystemMessage(t *testing.T) {
 	is.True(ok)
 	is.Equal(v.Message, msg)
 }
+
+func TestIssueEventTypeUserMessage(t *testing.T) {
+	is := is.New(t)
+
+	msg := `projector says "no signal"`
+	event := IssueEvent{
+		Type: TypeUserMessage,
+		Data: NewUserMessage("frontdesk1", msg),
+	}
+
+	data, err := event.ParseData()
+	is.NoErr(err)
+
+	v, ok := data.(UserMessage)
+	is.True(ok)
+	is.Equal(v.Message, msg)
+	is.Equal(v.User, "frontdesk1")
+}
+
+func TestAlertSeen(t *testing.T) {
+	is := is.New(t)
+
+	start := time.Date(2021, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	var alert Alert
+	alert.Seen("81.5", start)
+	alert.Seen("84", start.Add(time.Minute))
+
+	is.Equal(alert.FirstValue, "81.5")
+	is.Equal(alert.LastValue, "84")
+	is.Equal(alert.LastSeen, start.Add(time.Minute))
+	is.Equal(alert.Count, 2)
+}
@@ -0,0 +1,72 @@
+package smee
+
+import (
+	"context"
+	"time"
+)
+
+// NotificationType is the issue lifecycle event a Notification is about
+type NotificationType string
+
+const (
+	NotifyIssueCreated       NotificationType = "issue-created"
+	NotifyAlertAdded         NotificationType = "alert-added"
+	NotifyIssueAcknowledged  NotificationType = "issue-acknowledged"
+	NotifyIssueClosed        NotificationType = "issue-closed"
+	NotifyIssueStatusChanged NotificationType = "issue-status-changed"
+
+	// NotifyIssueEscalated is only sent directly to the notifier named by
+	// an escalation step, so it isn't in NotificationTypes
+	NotifyIssueEscalated NotificationType = "issue-escalated"
+)
+
+// NotificationTypes is every NotificationType
+var NotificationTypes = []NotificationType{
+	NotifyIssueCreated,
+	NotifyAlertAdded,
+	NotifyIssueAcknowledged,
+	NotifyIssueClosed,
+	NotifyIssueStatusChanged,
+}
+
+// Notification is sent to a Notifier when something happens to an issue
+type Notification struct {
+	Type      NotificationType `json:"type"`
+	Timestamp time.Time        `json:"timestamp"`
+	Issue     Issue            `json:"issue"`
+
+	// Alert is the alert that was added for NotifyIssueCreated and NotifyAlertAdded
+	Alert *Alert `json:"alert,omitempty"`
+
+	// Message describes why the notification was sent, if there is more to say than Type
+	Message string `json:"message,omitempty"`
+}
+
+// AlertTypes returns the alert types that n is about. That is the type of
+// n.Alert if it is set, otherwise the types of every alert on the issue.
+func (n Notification) AlertTypes() []string {
+	if n.Alert != nil {
+		return []string{n.Alert.Type}
+	}
+
+	seen := make(map[string]bool)
+	var types []string
+	for _, alert := range n.Issue.Alerts {
+		if !seen[alert.Type] {
+			seen[alert.Type] = true
+			types = append(types, alert.Type)
+		}
+	}
+
+	return types
+}
+
+// Notifier tells someone about issue lifecycle events
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// NamedNotifier sends a notification to a specific notifier, by name
+type NamedNotifier interface {
+	NotifyNamed(ctx context.Context, name string, n Notification) error
+}
@@ -0,0 +1,101 @@
+package smee
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ErrValueNotNumeric is returned when a transition has a numeric condition,
+// but the event's value can't be parsed as a number.
+var ErrValueNotNumeric = errors.New("value is not a number")
+
+type NumericOp string
+
+const (
+	// NumericGreaterThan matches values > Threshold
+	NumericGreaterThan NumericOp = "gt"
+	// NumericLessThan matches values < Threshold
+	NumericLessThan NumericOp = "lt"
+	// NumericAtLeast matches values >= Threshold
+	NumericAtLeast NumericOp = "gte"
+	// NumericAtMost matches values <= Threshold
+	NumericAtMost NumericOp = "lte"
+	// NumericBetween matches values in [Low, High]
+	NumericBetween NumericOp = "between"
+	// NumericOutside matches values < Low or > High
+	NumericOutside NumericOp = "outside"
+)
+
+// NumericCondition compares an event's value as a number.
+type NumericCondition struct {
+	Op NumericOp
+
+	// Threshold is used by gt, lt, gte, and lte
+	Threshold float64
+
+	// Low and High are the bounds used by between and outside
+	Low  float64
+	High float64
+}
+
+func (c NumericCondition) Matches(val float64) bool {
+	switch c.Op {
+	case NumericGreaterThan:
+		return val > c.Threshold
+	case NumericLessThan:
+		return val < c.Threshold
+	case NumericAtLeast:
+		return val >= c.Threshold
+	case NumericAtMost:
+		return val <= c.Threshold
+	case NumericBetween:
+		return val >= c.Low && val <= c.High
+	case NumericOutside:
+		return val < c.Low || val > c.High
+	default:
+		return false
+	}
+}
+
+// Hysteresis returns the condition that should close an alert opened by c,
+// leaving a band of width hysteresis between the two so that a value hovering
+// around the threshold doesn't repeatedly open and close the alert.
+func (c NumericCondition) Hysteresis(hysteresis float64) NumericCondition {
+	switch c.Op {
+	case NumericGreaterThan:
+		return NumericCondition{Op: NumericLessThan, Threshold: c.Threshold - hysteresis}
+	case NumericLessThan:
+		return NumericCondition{Op: NumericGreaterThan, Threshold: c.Threshold + hysteresis}
+	case NumericAtLeast:
+		return NumericCondition{Op: NumericLessThan, Threshold: c.Threshold - hysteresis}
+	case NumericAtMost:
+		return NumericCondition{Op: NumericGreaterThan, Threshold: c.Threshold + hysteresis}
+	case NumericBetween:
+		return NumericCondition{Op: NumericOutside, Low: c.Low - hysteresis, High: c.High + hysteresis}
+	case NumericOutside:
+		return NumericCondition{Op: NumericBetween, Low: c.Low + hysteresis, High: c.High - hysteresis}
+	default:
+		return c
+	}
+}
+
+func (c NumericCondition) String() string {
+	switch c.Op {
+	case NumericGreaterThan, NumericLessThan, NumericAtLeast, NumericAtMost:
+		return fmt.Sprintf("%s %g", c.Op, c.Threshold)
+	default:
+		return fmt.Sprintf("%s [%g, %g]", c.Op, c.Low, c.High)
+	}
+}
+
+func parseNumber(value string) (float64, error) {
+	val, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || math.IsNaN(val) {
+		return 0, fmt.Errorf("%w: %q", ErrValueNotNumeric, value)
+	}
+
+	return val, nil
+}
@@ -0,0 +1,59 @@
+package smee
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func TestNumericConditionHysteresis(t *testing.T) {
+	is := is.New(t)
+
+	create := NumericCondition{Op: NumericGreaterThan, Threshold: 80}
+	closing := create.Hysteresis(10)
+
+	is.True(create.Matches(85))
+	is.True(!closing.Matches(75)) // inside the band, nothing changes
+	is.True(!create.Matches(75))
+	is.True(closing.Matches(69.5))
+
+	atLeast := NumericCondition{Op: NumericAtLeast, Threshold: 90}
+	below := atLeast.Hysteresis(0)
+
+	is.True(atLeast.Matches(90))
+	is.True(!below.Matches(90))
+	is.True(below.Matches(89.9))
+
+	between := NumericCondition{Op: NumericBetween, Low: 61, High: 90}
+	outside := between.Hysteresis(0)
+
+	is.True(between.Matches(61))
+	is.True(between.Matches(90))
+	is.True(!outside.Matches(90))
+	is.True(outside.Matches(91))
+	is.True(outside.Matches(60))
+}
+
+func TestAlertTransitionEventNotNumeric(t *testing.T) {
+	is := is.New(t)
+
+	trans := AlertTransitionEvent{
+		KeyMatches: regexp.MustCompile("^battery-charge-minutes$"),
+		Value:      &NumericCondition{Op: NumericLessThan, Threshold: 30},
+	}
+
+	ok, err := trans.Matches(Event{Key: "battery-charge-minutes", Value: " 12 "})
+	is.NoErr(err)
+	is.True(ok)
+
+	ok, err = trans.Matches(Event{Key: "battery-charge-minutes", Value: "Calculating"})
+	is.True(errors.Is(err, ErrValueNotNumeric))
+	is.True(!ok)
+
+	// a key that doesn't match isn't an error, even if the value isn't a number
+	ok, err = trans.Matches(Event{Key: "power", Value: "on"})
+	is.NoErr(err)
+	is.True(!ok)
+}
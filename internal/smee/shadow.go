@@ -0,0 +1,38 @@
+package smee
+
+import (
+	"context"
+	"time"
+)
+
+// ShadowStore records what alert types in shadow mode would have done. It is
+// kept separate from the IssueStore so that shadow alerts never open issues.
+type ShadowStore interface {
+	// OpenShadowAlert records that alert would have been created, and returns it with its ID set
+	OpenShadowAlert(ctx context.Context, alert Alert, events ...IssueEvent) (Alert, error)
+
+	// CloseShadowAlert records that the shadow alert with alertID would have been closed
+	CloseShadowAlert(ctx context.Context, alertID string, events ...IssueEvent) (Alert, error)
+
+	// ActiveShadowAlerts returns every shadow alert that is still open
+	ActiveShadowAlerts(ctx context.Context) ([]Alert, error)
+
+	// ShadowTimeline returns what shadow alerts of typ would have done,
+	// oldest first. An empty typ returns every type.
+	ShadowTimeline(ctx context.Context, typ string) ([]ShadowEvent, error)
+}
+
+type ShadowAction string
+
+const (
+	ShadowWouldHaveFired  ShadowAction = "would-have-fired"
+	ShadowWouldHaveClosed ShadowAction = "would-have-closed"
+)
+
+// ShadowEvent is an entry in a shadow alert type's timeline
+type ShadowEvent struct {
+	Timestamp time.Time    `json:"timestamp"`
+	Action    ShadowAction `json:"action"`
+	Alert     Alert        `json:"alert"`
+	Events    []IssueEvent `json:"events,omitempty"`
+}
@@ -0,0 +1,89 @@
+package smee
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+type SilenceStore interface {
+	// Silences returns every silence that hasn't expired
+	Silences(context.Context) ([]Silence, error)
+	Silence(ctx context.Context, id string) (Silence, error)
+	CreateSilence(context.Context, Silence) (Silence, error)
+	UpdateSilence(context.Context, Silence) (Silence, error)
+	DeleteSilence(ctx context.Context, id string) error
+}
+
+// Silence keeps alerts that match all of its matchers from being created
+// between Start and End. An empty matcher matches every alert.
+type Silence struct {
+	ID string `json:"id"`
+
+	// RoomID must equal the alert's room
+	RoomID string `json:"roomID,omitempty"`
+
+	// DeviceID is a regular expression that must match the alert's whole device ID
+	DeviceID string `json:"deviceID,omitempty"`
+
+	// AlertType must equal the alert's type
+	AlertType string `json:"alertType,omitempty"`
+
+	CreatedBy string    `json:"createdBy"`
+	Comment   string    `json:"comment"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+}
+
+// Validate returns an error if s can't be stored
+func (s Silence) Validate() error {
+	switch {
+	case s.RoomID == "" && s.DeviceID == "" && s.AlertType == "":
+		return errors.New("at least one of roomID, deviceID, or alertType is required")
+	case s.CreatedBy == "":
+		return errors.New("createdBy is required")
+	case s.Comment == "":
+		return errors.New("comment is required")
+	case s.End.IsZero():
+		return errors.New("end is required")
+	case !s.Start.IsZero() && !s.End.After(s.Start):
+		return errors.New("end must be after start")
+	}
+
+	if _, err := s.deviceRegexp(); err != nil {
+		return fmt.Errorf("invalid deviceID: %w", err)
+	}
+
+	return nil
+}
+
+// Active returns true if s is in effect at t
+func (s Silence) Active(t time.Time) bool {
+	return !t.Before(s.Start) && t.Before(s.End)
+}
+
+// Matches returns true if every matcher on s matches alert. It doesn't check
+// whether s is active.
+func (s Silence) Matches(alert Alert) bool {
+	switch {
+	case s.RoomID != "" && s.RoomID != alert.Device.Room.ID:
+		return false
+	case s.AlertType != "" && s.AlertType != alert.Type:
+		return false
+	case s.DeviceID == "":
+		return true
+	}
+
+	reg, err := s.deviceRegexp()
+	if err != nil {
+		return false
+	}
+
+	return reg.MatchString(alert.Device.ID)
+}
+
+func (s Silence) deviceRegexp() (*regexp.Regexp, error) {
+	return regexp.Compile("^(?:" + s.DeviceID + ")$")
+}
@@ -0,0 +1,44 @@
+package smee
+
+import (
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+)
+
+func TestSilenceMatches(t *testing.T) {
+	is := is.New(t)
+
+	alert := Alert{
+		Device: Device{ID: "ITB-1101-CP1", Room: Room{ID: "ITB-1101"}},
+		Type:   "device-offline",
+	}
+
+	s := Silence{RoomID: "ITB-1101", DeviceID: "ITB-1101-CP[0-9]+"}
+	is.True(s.Matches(alert))
+
+	s.DeviceID = "CP1" // device IDs have to match completely
+	is.True(!s.Matches(alert))
+
+	s = Silence{AlertType: "sys-offline"}
+	is.True(!s.Matches(alert))
+
+	now := time.Now()
+	s = Silence{Start: now.Add(-time.Minute), End: now.Add(time.Minute)}
+	is.True(s.Active(now))
+	is.True(!s.Active(now.Add(time.Minute)))
+}
+
+func TestSilenceValidate(t *testing.T) {
+	is := is.New(t)
+
+	s := Silence{CreatedBy: "someone", Comment: "projector swap", End: time.Now().Add(time.Hour)}
+	is.True(s.Validate() != nil) // no matchers
+
+	s.DeviceID = "ITB-1101-("
+	is.True(s.Validate() != nil)
+
+	s.DeviceID = "ITB-1101-.*"
+	is.NoErr(s.Validate())
+}
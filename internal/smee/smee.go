@@ -13,6 +13,9 @@ type IncidentStore interface {
 	CreateIncident(context.Context, Incident) (Incident, error)
 }
 
+// IncidentCaller is the ServiceNow user that incidents are created as
+const IncidentCaller = "avmonit1"
+
 type Incident struct {
 	ID   string `json:"id"`
 	Name string `json:"name"`
@@ -42,8 +45,43 @@ type EventStream interface {
 }
 
 type DeviceStateStore interface {
-	// RunAlertQueries runs all store-specific queries and returns a map of queryName -> device id's that match the query
-	RunAlertQueries(ctx context.Context) (map[string][]Device, error)
+	// RunAlertQueries runs queries against the state of every device and returns a map of queryName -> devices that match the query.
+	// Every query has an entry in the map, even if no devices match it.
+	RunAlertQueries(ctx context.Context, queries map[string]DeviceStateQuery) (map[string][]Device, error)
+}
+
+// DeviceStateWatcher is a DeviceStateStore that can report changes to device
+// state as they happen, instead of waiting for the next RunAlertQueries
+type DeviceStateWatcher interface {
+	// WatchAlertQueries calls update with the queries a device matches each
+	// time its state changes, or enough time passes that a query's result
+	// on it may have changed. queries is called to get the current queries
+	// each time they are run. It blocks until ctx is done.
+	WatchAlertQueries(ctx context.Context, queries func() map[string]DeviceStateQuery, update func(DeviceStateUpdate)) error
+}
+
+// DeviceStateUpdate is the result of running the state queries against a device
+type DeviceStateUpdate struct {
+	Device Device
+
+	// Matches is the set of query names the device matches
+	Matches map[string]bool
+}
+
+// DeviceStateQuery matches a device's state document
+type DeviceStateQuery interface {
+	// Matches returns true if the device with state should have an alert at now
+	Matches(state map[string]interface{}, now time.Time) (bool, error)
+}
+
+// TimedDeviceStateQuery is a DeviceStateQuery whose result can change as
+// time passes, like "no heartbeat in the last 6 minutes"
+type TimedDeviceStateQuery interface {
+	DeviceStateQuery
+
+	// NextChange returns the earliest time after now that the query's result
+	// on state can change without state changing. ok is false if it can't.
+	NextChange(state map[string]interface{}, now time.Time) (next time.Time, ok bool)
 }
 
 type Room struct {
@@ -63,6 +101,66 @@ type AlertConfig struct {
 	// TODO Only have a a close for event alerts
 	Create AlertTransition
 	Close  AlertTransition
+
+	// For is how long the create transition has to hold (without the close
+	// transition matching) before the alert is created
+	For time.Duration
+
+	// Flapping overrides the alert manager's default flap detection
+	Flapping *FlapConfig
+
+	// TTL, if set, closes alerts that have been open for longer than TTL.
+	// Intended for alert types without a close transition.
+	TTL time.Duration
+
+	// Shadow writes alerts of this type to the ShadowStore instead of opening issues
+	Shadow bool
+}
+
+// StateAlertConfig configures alerts created from device state queries
+type StateAlertConfig struct {
+	// Query selects the devices that should have an alert
+	Query DeviceStateQuery
+
+	// Interval is how often every device is run through Query. Zero uses
+	// the alert manager's default.
+	Interval time.Duration
+
+	// ForScans is how many consecutive state query runs a device has to
+	// match before the alert is created
+	ForScans int
+
+	// Flapping overrides the alert manager's default flap detection
+	Flapping *FlapConfig
+
+	// Shadow writes alerts from this query to the ShadowStore instead of opening issues
+	Shadow bool
+}
+
+// InhibitRule keeps alerts of the Target types from being created in a room
+// while an alert of the Source type is active in that room
+type InhibitRule struct {
+	Source  string
+	Targets []string
+}
+
+// Inhibits returns true if typ is one of r's targets
+func (r InhibitRule) Inhibits(typ string) bool {
+	for _, target := range r.Targets {
+		if target == typ {
+			return true
+		}
+	}
+
+	return false
+}
+
+// FlapConfig configures flap detection. An alert that opens/closes
+// Transitions times within Window is flapping, and is held open until
+// it hasn't changed for Window. A zero Transitions disables detection.
+type FlapConfig struct {
+	Transitions int
+	Window      time.Duration
 }
 
 type AlertTransition struct {
@@ -74,6 +172,34 @@ type AlertTransitionEvent struct {
 	KeyDoesNotMatch   *regexp.Regexp
 	ValueMatches      *regexp.Regexp
 	ValueDoesNotMatch *regexp.Regexp
+
+	// Value, if set, requires the event's value to be a number that satisfies the condition
+	Value *NumericCondition
+}
+
+// Matches returns true if event satisfies every condition on t. If t has a
+// numeric condition and the event's key matches but its value isn't a number,
+// Matches returns false and an error wrapping ErrValueNotNumeric.
+func (t *AlertTransitionEvent) Matches(event Event) (bool, error) {
+	switch {
+	case t.KeyMatches != nil && !t.KeyMatches.MatchString(event.Key):
+		return false, nil
+	case t.KeyDoesNotMatch != nil && t.KeyDoesNotMatch.MatchString(event.Key):
+		return false, nil
+	case t.ValueMatches != nil && !t.ValueMatches.MatchString(event.Value):
+		return false, nil
+	case t.ValueDoesNotMatch != nil && t.ValueDoesNotMatch.MatchString(event.Value):
+		return false, nil
+	case t.Value == nil:
+		return true, nil
+	}
+
+	val, err := parseNumber(event.Value)
+	if err != nil {
+		return false, err
+	}
+
+	return t.Value.Matches(val), nil
 }
 
 // change to room/device ID's
@@ -86,12 +212,63 @@ type Alert struct {
 	End               time.Time `json:"end"`
 	Acknowledged_By   string    `json:"acknowledged_by"`
 	Acknowledged_Time time.Time `json:"acknowledge_time"`
+
+	// ParentID is the ID of the correlated alert this alert is a symptom of
+	ParentID string `json:"parentID,omitempty"`
+
+	// FirstValue and LastValue are the values of the first and latest
+	// events that matched the alert's create transition
+	FirstValue string `json:"firstValue,omitempty"`
+	LastValue  string `json:"lastValue,omitempty"`
+
+	// LastSeen is when the alert's condition was last seen, and Count is
+	// how many times it has been seen since the alert started
+	LastSeen time.Time `json:"lastSeen"`
+	Count    int       `json:"count"`
 }
 
 func (a *Alert) Active() bool {
 	return a.End.IsZero()
 }
 
+// Seen records that the alert's condition was seen again at t with value
+func (a *Alert) Seen(value string, t time.Time) {
+	if a.Count == 0 {
+		a.FirstValue = value
+	}
+
+	a.LastValue = value
+	a.LastSeen = t
+	a.Count++
+}
+
+// PendingAlert is an alert whose condition is true, but hasn't been
+// true for long enough to create the alert yet
+type PendingAlert struct {
+	Alert Alert `json:"alert"`
+
+	// Since is when the condition first became true
+	Since time.Time `json:"since"`
+
+	// FiresAt is when an event alert will be created if it isn't closed first
+	FiresAt time.Time `json:"firesAt,omitempty"`
+
+	// Scans and ScansRequired are set for device state alerts
+	Scans         int `json:"scans,omitempty"`
+	ScansRequired int `json:"scansRequired,omitempty"`
+}
+
 type AlertManager interface {
 	Run(context.Context) error
+	PendingAlerts(context.Context) ([]PendingAlert, error)
+
+	// CreateAlert queues alert to be created, recording that user created it
+	// with msg. It returns ErrInvalidAlertType if alerts of alert's type
+	// can't be created by hand.
+	CreateAlert(ctx context.Context, alert Alert, user, msg string) error
+
+	// CloseAlert queues the active alerts of typ on deviceID in roomID to be
+	// closed, recording that user closed them with msg. It returns
+	// ErrAlertNotFound if there aren't any.
+	CloseAlert(ctx context.Context, roomID, deviceID, typ, user, msg string) error
 }
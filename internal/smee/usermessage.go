@@ -0,0 +1,21 @@
+package smee
+
+import "encoding/json"
+
+// TypeUserMessage is an issue event written by a person (or an integration)
+const TypeUserMessage IssueEventType = "user-message"
+
+// UserMessage is the data of a TypeUserMessage issue event
+type UserMessage struct {
+	Message string `json:"msg"`
+	User    string `json:"user"`
+}
+
+func NewUserMessage(user, msg string) json.RawMessage {
+	data, _ := json.Marshal(UserMessage{
+		Message: msg,
+		User:    user,
+	})
+
+	return data
+}
@@ -0,0 +1 @@
+DROP TABLE alert_actions;
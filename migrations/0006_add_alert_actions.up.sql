@@ -0,0 +1,7 @@
+CREATE TABLE alert_actions (
+	id integer PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+	action text NOT NULL,
+	alert jsonb NOT NULL,
+	events jsonb,
+	created_at timestamptz NOT NULL
+);
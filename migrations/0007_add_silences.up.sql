@@ -0,0 +1,10 @@
+CREATE TABLE silences (
+	id integer PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+	room_id text NOT NULL DEFAULT '',
+	device_id text NOT NULL DEFAULT '',
+	alert_type text NOT NULL DEFAULT '',
+	created_by text NOT NULL,
+	comment text NOT NULL,
+	start_time timestamptz NOT NULL,
+	end_time timestamptz NOT NULL
+);
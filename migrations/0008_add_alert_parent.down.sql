@@ -0,0 +1,2 @@
+ALTER TABLE alerts
+DROP COLUMN parent_alert_id;
@@ -0,0 +1,2 @@
+ALTER TABLE alerts
+ADD parent_alert_id integer REFERENCES alerts (id) ON DELETE SET NULL;
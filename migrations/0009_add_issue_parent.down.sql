@@ -0,0 +1,2 @@
+ALTER TABLE issues
+DROP COLUMN parent_issue_id;
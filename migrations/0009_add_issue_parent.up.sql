@@ -0,0 +1,2 @@
+ALTER TABLE issues
+ADD parent_issue_id integer REFERENCES issues (id) ON DELETE SET NULL;
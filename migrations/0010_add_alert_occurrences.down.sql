@@ -0,0 +1,5 @@
+ALTER TABLE alerts
+DROP COLUMN first_value,
+DROP COLUMN last_value,
+DROP COLUMN last_seen,
+DROP COLUMN occurrences;
@@ -0,0 +1,5 @@
+ALTER TABLE alerts
+ADD first_value text,
+ADD last_value text,
+ADD last_seen timestamptz,
+ADD occurrences integer NOT NULL DEFAULT 1;
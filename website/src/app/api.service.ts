@@ -13,6 +13,11 @@ export interface Alert {
   link: string | undefined;
   acknowledgedBy: string | undefined;
   acknowledgedTime: Date | undefined;
+  parentID: string | undefined;
+  firstValue: string | undefined;
+  lastValue: string | undefined;
+  lastSeen: Date | undefined;
+  count: number;
 }
 
 export interface Room {
@@ -51,6 +56,7 @@ export interface Issue {
   acknowledgedBy: string | undefined;
   acknowledgedTime: Date | undefined;
   status: string | undefined;
+  parentID: string | undefined;
 }
 
 export interface MaintenanceInfo {
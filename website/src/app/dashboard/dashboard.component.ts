@@ -25,6 +25,9 @@ export class DashboardComponent implements OnInit, OnDestroy, AfterViewInit {
   totalAlerts: number = 0;
   totalIssues: number = 0;
 
+  // issueRooms is a map of issueID -> room ID, used to show the building issue a room issue is linked to
+  issueRooms: Map<string, string> = new Map();
+
   @ViewChild('akwPaginator') akwPaginator: MatPaginator | null = null;
   @ViewChild('akwTable', {read: MatSort, static: true}) akwSort: MatSort | null = null;
 
@@ -161,6 +164,7 @@ export class DashboardComponent implements OnInit, OnDestroy, AfterViewInit {
       this.dataSource.data = acknowledgedIssues;
       this.unacknowledgedDataSource.data = unacknowledgedIssues;
       this.totalIssues = issues.length;
+      this.issueRooms = new Map(issues.map(i => [i.id, i.room.id] as [string, string]));
       this.totalAlerts = 0;
       for (let index = 0; index < issues.length; index++) {
         this.totalAlerts += this.getActiveAlerts(issues[index]);
@@ -168,6 +172,14 @@ export class DashboardComponent implements OnInit, OnDestroy, AfterViewInit {
     })
   }
 
+  parentRoom(issue: Issue): string | undefined {
+    if (!issue.parentID) {
+      return undefined;
+    }
+
+    return this.issueRooms.get(issue.parentID);
+  }
+
   acknowledgeIssue(issue: Issue): void {
     this.api.acknowledgeIssue(issue).subscribe(issue => {
     }, err => {
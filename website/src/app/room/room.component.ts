@@ -34,7 +34,7 @@ interface ErrorData {
   styleUrls: ['./room.component.scss']
 })
 export class RoomComponent implements OnInit, OnDestroy, AfterViewInit {
-  alertColumns: string[] = ["device", "type", "start", "end", "serviceNow"];
+  alertColumns: string[] = ["device", "type", "value", "start", "end", "serviceNow"];
   updateInterval: number | undefined;
   alertsDataSource: MatTableDataSource<Alert> = new MatTableDataSource(undefined);
   roomID: string = "";
@@ -66,7 +66,7 @@ export class RoomComponent implements OnInit, OnDestroy, AfterViewInit {
     
     this.alertsDataSource.sortData = (data: Alert[], sort: MatSort): Alert[] => {
       if (!sort.active || sort.direction === ''){
-        return data;
+        return this.rootCausesFirst(data);
       }
       const isAsc = sort.direction === 'asc';
 
@@ -82,14 +82,15 @@ export class RoomComponent implements OnInit, OnDestroy, AfterViewInit {
         return (a < b ? -1 : 1) * (isAsc ? 1 : -1);
       }
 
-      return data.sort((a, b) => {
+      return this.rootCausesFirst(data.sort((a, b) => {
         switch (sort.active) {
           case 'type': return cmp (a.type, b.type);
+          case 'value': return cmp (a.lastSeen, b.lastSeen);
           case 'start': return cmp (a.start, b.start);
           case 'end': return cmp (a.end, b.end);
           default: return 0;
         }
-      });
+      }));
     }
 
   }
@@ -119,6 +120,37 @@ export class RoomComponent implements OnInit, OnDestroy, AfterViewInit {
     });
   }
 
+  // rootCausesFirst moves correlated alerts (like a room outage) to the top,
+  // each followed by the device alerts it is the probable cause of. The
+  // order of sorted is kept within each group.
+  private rootCausesFirst(sorted: Alert[]): Alert[] {
+    const ids = new Set(sorted.map(a => a.id));
+    const children = new Map<string, Alert[]>();
+    const roots: Alert[] = [];
+
+    for (const alert of sorted) {
+      if (alert.parentID && ids.has(alert.parentID)) {
+        children.set(alert.parentID, [...(children.get(alert.parentID) || []), alert]);
+      } else {
+        roots.push(alert);
+      }
+    }
+
+    const causes = roots.filter(a => children.has(a.id));
+    const others = roots.filter(a => !children.has(a.id));
+
+    const res: Alert[] = [];
+    for (const root of [...causes, ...others]) {
+      res.push(root, ...(children.get(root.id) || []));
+    }
+
+    return res;
+  }
+
+  isChild(alert: Alert): boolean {
+    return !!alert.parentID && !!this.issue?.alerts?.has(alert.parentID);
+  }
+
   IssueTypeUrl(alert : Alert): string{
     const IssueMap = this.issueType?.IssueType
     const url = "https://it.byu.edu/nav_to.do?uri=kb_view.do?sysparm_article="